@@ -1,10 +1,13 @@
 package api
 
 import (
+	"backend/htmlcheck"
 	"backend/jobs"
+	"backend/models"
 	"backend/services"
 	"backend/utils"
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -23,11 +26,56 @@ func AnalyzeHandler(c *gin.Context) {
 	var req struct {
 		URL  string `json:"url"`
 		HTML string `json:"html"`
+		// Optional cookies, headers, basicAuth and loginSteps for pages
+		// behind a login.
+		models.ScanAuth
+		// Optional axe settings: standard, bestPractices, enableRules,
+		// disableRules, include and exclude.
+		Options *models.ScanOptions `json:"options"`
+		// Device is desktop, tablet, mobile or custom; a custom device takes
+		// its width, height, deviceScaleFactor and userAgent from Viewport.
+		// Devices scans the page once per profile instead.
+		Device   string                `json:"device"`
+		Devices  []string              `json:"devices"`
+		Viewport *models.DeviceProfile `json:"viewport"`
+		// Engine is axe (default) or static, which checks html without a
+		// browser.
+		Engine models.ScanEngine `json:"engine"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || (req.URL == "" && req.HTML == "") {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Must provide url or html", "error": err})
 		return
 	}
+	if req.URL != "" {
+		// Baselines are matched by URL, so it is stored the way crawls and
+		// schedules spell it.
+		u, _, err := services.NormalizeURL(req.URL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "url must be an http or https URL"})
+			return
+		}
+		req.URL = u
+	}
+	if err := checkEngine(req.Engine, req.URL, req.Options, req.Device != "" || len(req.Devices) > 0 || req.Viewport != nil); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	var auth *models.ScanAuth
+	if !req.ScanAuth.IsEmpty() {
+		if req.URL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Login details can only be used when scanning a url"})
+			return
+		}
+		if err := req.ScanAuth.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+		if !utils.SecretsConfigured() {
+			c.JSON(http.StatusNotImplemented, gin.H{"success": false, "message": "Authenticated scanning is not configured on this server"})
+			return
+		}
+		auth = &req.ScanAuth
+	}
 	claims, exists := c.Get("claims")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
@@ -36,22 +84,153 @@ func AnalyzeHandler(c *gin.Context) {
 	userClaims := claims.(jwt.MapClaims)
 	userID, _ := primitive.ObjectIDFromHex(userClaims["user_id"].(string))
 
-	report, err := services.CreateReport(context.Background(), userID, req.URL, req.HTML)
+	if req.Options.IsEmpty() {
+		req.Options = nil
+	} else if err := req.Options.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	devices, err := requestedDevices(req.Device, req.Devices, req.Viewport)
 	if err != nil {
-		utils.LogAction(userID.Hex(), "analyze", "failure", "failed to create report")
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to create report"})
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	// Reports of a multi-device scan share a group id so they can be listed
+	// side by side.
+	var groupID *primitive.ObjectID
+	if len(req.Devices) > 0 {
+		id := primitive.NewObjectID()
+		groupID = &id
+	}
+	var started []gin.H
+	// failed answers a request that broke off partway; the reports of a group
+	// that did start keep running, so they are returned along with the error.
+	failed := func(message string) {
+		resp := gin.H{"success": false, "message": message}
+		if groupID != nil && len(started) > 0 {
+			resp["data"] = gin.H{"groupId": groupID, "reports": started}
+		}
+		c.JSON(http.StatusInternalServerError, resp)
+	}
+	for _, device := range devices {
+		report := services.NewReport(userID, req.URL, req.HTML)
+		report.Authenticated = auth != nil
+		report.ScanOptions = req.Options
+		report.Device = device
+		report.GroupID = groupID
+		report.Engine = req.Engine
+		report, err := services.InsertReport(context.Background(), report)
+		if err != nil {
+			utils.LogAction(userID.Hex(), "analyze", "failure", "failed to create report")
+			failed("Failed to create report")
+			return
+		}
+		err = jobs.EnqueueAnalyzeJob(context.Background(), jobs.AnalyzeJob{
+			ReportID: report.ID,
+			UserID:   userID,
+			URL:      req.URL,
+			HTML:     req.HTML,
+			Auth:     auth,
+			Options:  req.Options,
+			Device:   device,
+			Engine:   req.Engine,
+		})
+		if err != nil {
+			utils.LogAction(userID.Hex(), "analyze", "failure", "failed to enqueue job: "+err.Error())
+			_ = services.FailReport(context.Background(), report.ID, "Failed to enqueue job")
+			failed("Failed to queue analysis")
+			return
+		}
+		utils.LogAction(userID.Hex(), "analyze", "success", "enqueued analysis job")
+		started = append(started, gin.H{"reportId": report.ID, "status": report.Status, "createdAt": report.CreatedAt, "device": report.Device})
+	}
+	if groupID == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Analysis started",
+			"data":    started[0],
+			"error":   nil,
+		})
 		return
 	}
-	jobs.EnqueueAnalyzeJob(jobs.AnalyzeJob{
-		ReportID: report.ID,
-		URL:      req.URL,
-		HTML:     req.HTML,
-	})
-	utils.LogAction(userID.Hex(), "analyze", "success", "enqueued analysis job")
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Analysis started",
-		"data":    gin.H{"reportId": report.ID, "status": report.Status, "createdAt": report.CreatedAt},
+		"data":    gin.H{"groupId": groupID, "reports": started},
 		"error":   nil,
 	})
 }
+
+// maxScanDevices caps how many profiles one multi-device scan may ask for.
+const maxScanDevices = 5
+
+// requestedDevices resolves the device profiles of an analyze request. The
+// result holds a single nil profile when none was asked for, so the runner
+// keeps its default viewport.
+func requestedDevices(device string, names []string, viewport *models.DeviceProfile) ([]*models.DeviceProfile, error) {
+	if len(names) == 0 {
+		if device == "" {
+			if viewport != nil {
+				device = models.DeviceCustom
+			} else {
+				return []*models.DeviceProfile{nil}, nil
+			}
+		}
+		profile, err := models.ResolveDevice(device, viewport)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.DeviceProfile{profile}, nil
+	}
+	if device != "" {
+		return nil, fmt.Errorf("use either device or devices, not both")
+	}
+	if len(names) > maxScanDevices {
+		return nil, fmt.Errorf("at most %d devices can be scanned at once", maxScanDevices)
+	}
+	seen := map[string]bool{}
+	var profiles []*models.DeviceProfile
+	for _, name := range names {
+		profile, err := models.ResolveDevice(name, viewport)
+		if err != nil {
+			return nil, err
+		}
+		if seen[profile.Name] {
+			return nil, fmt.Errorf("device %q is listed twice", profile.Name)
+		}
+		seen[profile.Name] = true
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// checkEngine rejects settings the chosen engine cannot honour. The static
+// engine only reads submitted html and has no browser to size or log in with.
+func checkEngine(engine models.ScanEngine, url string, opts *models.ScanOptions, device bool) error {
+	if !engine.IsValid() {
+		return fmt.Errorf("unknown engine %q, expected axe or static", engine)
+	}
+	if engine != models.ScanEngineStatic {
+		return nil
+	}
+	switch {
+	case url != "":
+		return fmt.Errorf("the static engine only checks html, not a url")
+	case device:
+		return fmt.Errorf("device profiles need the axe engine")
+	case opts != nil && (len(opts.Include) > 0 || len(opts.Exclude) > 0):
+		return fmt.Errorf("include and exclude need the axe engine")
+	}
+	if opts != nil {
+		known := map[string]bool{}
+		for _, id := range htmlcheck.RuleIDs() {
+			known[id] = true
+		}
+		for _, id := range append(append([]string{}, opts.EnableRules...), opts.DisableRules...) {
+			if !known[id] {
+				return fmt.Errorf("rule %q is not checked by the static engine", id)
+			}
+		}
+	}
+	return nil
+}
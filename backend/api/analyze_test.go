@@ -0,0 +1,59 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"backend/models"
+)
+
+func TestRequestedDevices(t *testing.T) {
+	viewport := &models.DeviceProfile{Width: 1024, Height: 768}
+	tests := []struct {
+		name     string
+		device   string
+		names    []string
+		viewport *models.DeviceProfile
+		want     []string
+		wantErr  string
+	}{
+		{name: "nothing asked for", want: []string{""}},
+		{name: "empty list", names: []string{}, want: []string{""}},
+		{name: "device", device: "mobile", want: []string{"mobile"}},
+		{name: "viewport only", viewport: viewport, want: []string{"custom"}},
+		{name: "unknown device", device: "watch", wantErr: "unknown device"},
+		{name: "list", names: []string{"desktop", "mobile"}, want: []string{"desktop", "mobile"}},
+		{name: "list with custom", names: []string{"custom", "tablet"}, viewport: viewport, want: []string{"custom", "tablet"}},
+		{name: "unknown in list", names: []string{"desktop", "watch"}, wantErr: `unknown device "watch"`},
+		{name: "empty name in list", names: []string{"desktop", ""}, wantErr: `unknown device ""`},
+		{name: "duplicate", names: []string{"mobile", "desktop", "mobile"}, wantErr: `device "mobile" is listed twice`},
+		{name: "duplicate after normalising", names: []string{"mobile", " MOBILE"}, wantErr: "listed twice"},
+		{name: "device and list", device: "mobile", names: []string{"desktop"}, wantErr: "either device or devices"},
+		{name: "too many", names: []string{"desktop", "tablet", "mobile", "custom", "desktop", "tablet"}, viewport: viewport, wantErr: "at most 5 devices"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profiles, err := requestedDevices(tt.device, tt.names, tt.viewport)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("requestedDevices() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("requestedDevices(): %v", err)
+			}
+			var got []string
+			for _, p := range profiles {
+				if p == nil {
+					got = append(got, "")
+				} else {
+					got = append(got, p.Name)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") || len(got) != len(tt.want) {
+				t.Errorf("requestedDevices() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"backend/services"
+	"backend/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func RegisterBaselineRoutes(router *gin.Engine) {
+	baselines := router.Group("/api/baselines")
+	baselines.Use(AuthMiddleware())
+	{
+		baselines.GET("", ListBaselinesHandler)
+		baselines.DELETE(":id", DeleteBaselineHandler)
+	}
+}
+
+// PinBaselineHandler makes a finished report the baseline for its URL
+func PinBaselineHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	reportID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid report id"})
+		return
+	}
+	report, err := services.GetReportByID(c.Request.Context(), reportID)
+	if err != nil || report.UserID != userID {
+		utils.LogAction(userID.Hex(), "pin_baseline", "failure", "not found or forbidden")
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Report not found"})
+		return
+	}
+	if report.URL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Only reports for a URL can be pinned as a baseline"})
+		return
+	}
+	if report.AnalysisResults == nil {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": "Report has no results yet", "data": gin.H{"status": report.Status}})
+		return
+	}
+	baseline, err := services.PinBaseline(c.Request.Context(), report)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "pin_baseline", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to pin baseline"})
+		return
+	}
+	utils.LogAction(userID.Hex(), "pin_baseline", "success", "pinned report "+reportID.Hex()+" for "+report.URL)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": baseline})
+}
+
+func ListBaselinesHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	baselines, err := services.ListBaselinesByUser(c.Request.Context(), userID)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "list_baselines", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch baselines"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": baselines})
+}
+
+// DeleteBaselineHandler unpins a baseline. Reports already flagged against it
+// keep their regressions.
+func DeleteBaselineHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	baselineID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid baseline id"})
+		return
+	}
+	deleted, err := services.DeleteBaseline(c.Request.Context(), baselineID, userID)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "delete_baseline", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to delete baseline"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Baseline not found"})
+		return
+	}
+	utils.LogAction(userID.Hex(), "delete_baseline", "success", "deleted baseline "+baselineID.Hex())
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Baseline deleted."})
+}
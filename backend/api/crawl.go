@@ -0,0 +1,345 @@
+package api
+
+import (
+	"backend/crawler"
+	"backend/jobs"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxURLListSize caps an uploaded URL list.
+	maxURLListSize    = 10 << 20
+	defaultCrawlPages = 50
+	defaultCrawlDepth = 3
+	maxCrawlDepth     = 10
+)
+
+// maxCrawlPages caps maxPages on a single crawl.
+var maxCrawlPages = utils.EnvInt("CRAWL_MAX_PAGES", 500)
+
+func RegisterCrawlRoutes(router *gin.Engine) {
+	crawls := router.Group("/api/crawls")
+	crawls.Use(AuthMiddleware())
+	{
+		crawls.POST("", CreateCrawlHandler)
+		crawls.POST("/sitemap", CreateSitemapCrawlHandler)
+		crawls.POST("/urls", CreateURLListCrawlHandler)
+		crawls.GET("", ListCrawlsHandler)
+		crawls.GET(":id", GetCrawlHandler)
+	}
+}
+
+func CreateCrawlHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	var req crawlRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.StartURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Must provide startUrl"})
+		return
+	}
+	req.Source = models.CrawlSourceLinks
+	cfg, err := req.config()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	crawl := &models.Crawl{
+		UserID:   userID,
+		StartURL: cfg.StartURL,
+		Domain:   services.URLDomain(cfg.StartURL),
+		Source:   cfg.Source,
+		MaxPages: cfg.MaxPages,
+		MaxDepth: cfg.MaxDepth,
+		Include:  cfg.Include,
+		Exclude:  cfg.Exclude,
+	}
+	startCrawlJob(c, userID, crawl)
+}
+
+// crawlRequest is the crawl configuration accepted by POST /api/crawls and
+// by schedules. Source is links (the default) or sitemap, in which case
+// StartURL is the sitemap's URL.
+type crawlRequest struct {
+	Source   models.CrawlSource `json:"source"`
+	StartURL string             `json:"startUrl"`
+	MaxPages int                `json:"maxPages"`
+	MaxDepth *int               `json:"maxDepth"`
+	Include  []string           `json:"include"`
+	Exclude  []string           `json:"exclude"`
+}
+
+// config validates the request and fills in defaults. Its errors are meant
+// for the caller.
+func (r crawlRequest) config() (models.CrawlConfig, error) {
+	cfg := models.CrawlConfig{Source: r.Source, Include: r.Include, Exclude: r.Exclude}
+	switch cfg.Source {
+	case "":
+		cfg.Source = models.CrawlSourceLinks
+	case models.CrawlSourceLinks, models.CrawlSourceSitemap:
+	default:
+		return cfg, errors.New("source must be links or sitemap")
+	}
+	startURL, _, err := services.NormalizeURL(r.StartURL)
+	if err != nil {
+		return cfg, errors.New("startUrl must be an http or https URL")
+	}
+	cfg.StartURL = startURL
+	if cfg.MaxPages, err = checkMaxPages(r.MaxPages); err != nil {
+		return cfg, err
+	}
+	cfg.MaxDepth = defaultCrawlDepth
+	if r.MaxDepth != nil {
+		cfg.MaxDepth = *r.MaxDepth
+	}
+	if cfg.MaxDepth < 0 || cfg.MaxDepth > maxCrawlDepth {
+		return cfg, errors.New("maxDepth must be between 0 and " + strconv.Itoa(maxCrawlDepth))
+	}
+	for _, patterns := range [][]string{cfg.Include, cfg.Exclude} {
+		if _, err := jobs.CompilePatterns(patterns); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, nil
+}
+
+// CreateSitemapCrawlHandler scans the pages listed in a sitemap or sitemap
+// index, plain or gzipped
+func CreateSitemapCrawlHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	var req struct {
+		SitemapURL string   `json:"sitemapUrl"`
+		MaxPages   int      `json:"maxPages"`
+		Include    []string `json:"include"`
+		Exclude    []string `json:"exclude"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.SitemapURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Must provide sitemapUrl"})
+		return
+	}
+	sitemapURL, domain, err := services.NormalizeURL(req.SitemapURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "sitemapUrl must be an http or https URL"})
+		return
+	}
+	maxPages, err := checkMaxPages(req.MaxPages)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	for _, patterns := range [][]string{req.Include, req.Exclude} {
+		if _, err := jobs.CompilePatterns(patterns); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+	}
+	crawl := &models.Crawl{
+		UserID:   userID,
+		Source:   models.CrawlSourceSitemap,
+		StartURL: sitemapURL,
+		Domain:   domain,
+		MaxPages: maxPages,
+		Include:  req.Include,
+		Exclude:  req.Exclude,
+	}
+	startCrawlJob(c, userID, crawl)
+}
+
+// CreateURLListCrawlHandler scans every URL in an uploaded text or CSV file,
+// sent as the multipart field "file" or as a text/plain or text/csv body.
+// Options go in the query string: maxPages, include and exclude.
+func CreateURLListCrawlHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Must upload a file"})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Failed to read upload"})
+			return
+		}
+		defer file.Close()
+		body = file
+	} else if ct := c.ContentType(); ct != "text/plain" && ct != "text/csv" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"success": false, "message": "Upload a text or CSV file"})
+		return
+	}
+	// Read one byte past the limit so an oversized list is refused rather
+	// than cut off part way through a URL.
+	data, err := io.ReadAll(io.LimitReader(body, maxURLListSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Failed to read upload"})
+		return
+	}
+	if len(data) > maxURLListSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"success": false, "message": fmt.Sprintf("URL list is larger than %d MB", maxURLListSize>>20)})
+		return
+	}
+	urls, err := crawler.ParseURLList(bytes.NewReader(data))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Failed to parse URL list: " + err.Error()})
+		return
+	}
+	requested, _ := strconv.Atoi(c.Query("maxPages"))
+	maxPages, err := checkMaxPages(requested)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	include, exclude := c.QueryArray("include"), c.QueryArray("exclude")
+	pages, skipped, err := jobs.SelectListPages(urls, maxPages, include, exclude)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if len(pages) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "No http or https URLs to scan", "data": gin.H{"skipped": skipped}})
+		return
+	}
+
+	crawl := &models.Crawl{
+		UserID:   userID,
+		Source:   models.CrawlSourceList,
+		Domain:   commonDomain(pages),
+		MaxPages: maxPages,
+		Include:  include,
+		Exclude:  exclude,
+		Skipped:  skipped,
+	}
+	if err := services.CreateCrawl(context.Background(), crawl); err != nil {
+		utils.LogAction(userID.Hex(), "crawl", "failure", "failed to create crawl")
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to create crawl"})
+		return
+	}
+	if err := jobs.StartURLListCrawl(context.Background(), crawl, pages); err != nil {
+		utils.LogAction(userID.Hex(), "crawl", "failure", "failed to queue URL list: "+err.Error())
+		_ = services.FailCrawl(context.Background(), crawl.ID, "Failed to queue all pages")
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to queue URL list"})
+		return
+	}
+	utils.LogAction(userID.Hex(), "crawl", "success", "queued URL list of "+strconv.Itoa(len(pages))+" pages")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Scans started",
+		"data":    gin.H{"crawlId": crawl.ID, "pages": len(pages), "skipped": skipped, "createdAt": crawl.CreatedAt},
+	})
+}
+
+// checkMaxPages applies the default and limit to a requested maxPages.
+func checkMaxPages(requested int) (int, error) {
+	if requested <= 0 {
+		return defaultCrawlPages, nil
+	}
+	if requested > maxCrawlPages {
+		return 0, errors.New("maxPages may be at most " + strconv.Itoa(maxCrawlPages))
+	}
+	return requested, nil
+}
+
+// startCrawlJob stores a crawl and queues its discovery job.
+func startCrawlJob(c *gin.Context, userID primitive.ObjectID, crawl *models.Crawl) {
+	if err := jobs.StartCrawl(context.Background(), crawl); err != nil {
+		utils.LogAction(userID.Hex(), "crawl", "failure", "failed to start crawl: "+err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to queue crawl"})
+		return
+	}
+	utils.LogAction(userID.Hex(), "crawl", "success", "enqueued "+string(crawl.Source)+" crawl of "+crawl.StartURL)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Crawl started",
+		"data":    gin.H{"crawlId": crawl.ID, "status": crawl.Status, "createdAt": crawl.CreatedAt},
+	})
+}
+
+// commonDomain returns the domain shared by all urls, or "" for a mix.
+func commonDomain(urls []string) string {
+	domain := services.URLDomain(urls[0])
+	for _, u := range urls[1:] {
+		if services.URLDomain(u) != domain {
+			return ""
+		}
+	}
+	return domain
+}
+
+func ListCrawlsHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	crawls, err := services.ListCrawlsByUser(c.Request.Context(), userID)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "list_crawls", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch crawls"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": crawls})
+}
+
+// GetCrawlHandler returns a crawl with its pages and a summary of their
+// results so far.
+func GetCrawlHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	crawlID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid crawl id"})
+		return
+	}
+	ctx := c.Request.Context()
+	crawl, err := services.GetCrawlByID(ctx, crawlID)
+	if err != nil || crawl.UserID != userID {
+		utils.LogAction(userID.Hex(), "get_crawl", "failure", "not found or forbidden")
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Crawl not found"})
+		return
+	}
+	if crawl.Summary == nil {
+		// Pages finish independently, so an unfinished crawl is summarised
+		// from the reports as they are now.
+		summary, err := services.SummarizeCrawl(ctx, crawlID)
+		if err != nil {
+			utils.LogAction(userID.Hex(), "get_crawl", "failure", err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to summarise crawl"})
+			return
+		}
+		crawl.Summary = summary
+	}
+	pages, err := services.ListReportsByCrawl(ctx, crawlID)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "get_crawl", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch crawl pages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"crawl": crawl, "pages": pages}})
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"backend/services"
+	"backend/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterDomainRoutes(router *gin.Engine) {
+	domains := router.Group("/api/domains")
+	domains.Use(AuthMiddleware())
+	{
+		domains.GET(":domain/issues", ListDomainIssuesHandler)
+	}
+}
+
+// ListDomainIssuesHandler lists the violations shared by pages of a domain,
+// so a template bug shows up once with the number of pages it affects.
+// minPages (default 1) hides issues on fewer pages; limit defaults to 100.
+func ListDomainIssuesHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	minPages, err := strconv.Atoi(c.DefaultQuery("minPages", "1"))
+	if err != nil || minPages < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "minPages must be a positive number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "limit must be between 1 and 1000"})
+		return
+	}
+	domain := c.Param("domain")
+	issues, pages, err := services.ListDomainIssues(c.Request.Context(), userID, domain, minPages, limit)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "list_domain_issues", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch issues"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"domain": domain, "pages": pages, "issues": issues}})
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"backend/jobs"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func RegisterJobRoutes(router *gin.Engine) {
+	jobRoutes := router.Group("/api/jobs")
+	jobRoutes.Use(AuthMiddleware())
+	{
+		jobRoutes.GET("/stats", JobStatsHandler)
+		jobRoutes.GET("/dead", ListDeadJobsHandler)
+		jobRoutes.POST("/:id/requeue", RequeueJobHandler)
+	}
+}
+
+// JobStatsHandler reports worker pool usage and queue depth
+func JobStatsHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	queued, err := services.CountJobs(c.Request.Context(), bson.M{"status": models.JobStatusQueued})
+	if err != nil {
+		utils.LogAction(userID.Hex(), "job_stats", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch job stats"})
+		return
+	}
+	mine, err := services.CountJobs(c.Request.Context(), bson.M{
+		"userId": userID,
+		"status": bson.M{"$in": bson.A{models.JobStatusQueued, models.JobStatusLeased}},
+	})
+	if err != nil {
+		utils.LogAction(userID.Hex(), "job_stats", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch job stats"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{
+		"workers":    jobs.Stats(),
+		"queued":     queued,
+		"activeMine": mine,
+	}})
+}
+
+// ListDeadJobsHandler lists the user's jobs that failed for good
+func ListDeadJobsHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	deadJobs, err := services.ListJobsByUser(c.Request.Context(), userID, models.JobStatusDead)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "list_dead_jobs", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch dead jobs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": deadJobs})
+}
+
+// RequeueJobHandler puts a dead-lettered job back in the queue
+func RequeueJobHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	jobID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid job id"})
+		return
+	}
+	job, err := jobs.RequeueDeadJob(c.Request.Context(), jobID, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		utils.LogAction(userID.Hex(), "requeue_job", "failure", "not found or not dead")
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Dead job not found"})
+		return
+	}
+	if errors.Is(err, services.ErrJobCredentialsDiscarded) {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": "This scan's login details were discarded when it failed; start a new scan"})
+		return
+	}
+	if errors.Is(err, services.ErrJobActive) {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": "This report is already queued or being scanned"})
+		return
+	}
+	if err != nil {
+		utils.LogAction(userID.Hex(), "requeue_job", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to requeue job"})
+		return
+	}
+	utils.LogAction(userID.Hex(), "requeue_job", "success", "requeued job "+jobID.Hex())
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": job})
+}
@@ -1,13 +1,21 @@
 package api
 
 import (
+	"backend/jobs"
+	"backend/models"
 	"backend/services"
 	"backend/utils"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func RegisterReportRoutes(router *gin.Engine) {
@@ -17,7 +25,14 @@ func RegisterReportRoutes(router *gin.Engine) {
 		reports.GET("", ListReportsHandler)
 		reports.GET(":id", GetReportHandler)
 		reports.DELETE(":id", DeleteReportHandler)
+		reports.POST(":id/cancel", CancelReportHandler)
 		reports.GET(":id/suggestions", GetSuggestionsHandler)
+		reports.GET(":id/events", ReportEventsHandler)
+		reports.GET(":id/diff/:otherId", DiffReportsHandler)
+		reports.POST(":id/baseline", PinBaselineHandler)
+		reports.GET(":id/screenshots/:imageId", GetScreenshotHandler)
+		reports.GET(":id/dom", GetReportDOMHandler)
+		reports.POST(":id/rescan", RescanReportHandler)
 	}
 }
 
@@ -40,7 +55,22 @@ func ListReportsHandler(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
 		return
 	}
-	reports, err := services.ListReportsByUser(c.Request.Context(), userID)
+	sortKey := c.DefaultQuery("sort", "createdAt")
+	if _, ok := services.ReportSortFields[sortKey]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid sort field"})
+		return
+	}
+	ascending := c.Query("order") == "asc"
+	var groupID *primitive.ObjectID
+	if raw := c.Query("groupId"); raw != "" {
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid group id"})
+			return
+		}
+		groupID = &id
+	}
+	reports, err := services.ListReportsByUser(c.Request.Context(), userID, groupID, sortKey, ascending)
 	if err != nil {
 		utils.LogAction(userID.Hex(), "list_reports", "failure", err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch reports"})
@@ -86,16 +116,74 @@ func DeleteReportHandler(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Report not found"})
 		return
 	}
+	// Stop the scan first so no worker writes results, screenshots or
+	// suggestions for the report after it is gone.
+	err = jobs.CancelAnalyzeJob(c.Request.Context(), reportID)
+	if errors.Is(err, jobs.ErrNotCancellable) {
+		err = services.CancelJobsForReport(c.Request.Context(), reportID)
+	}
+	if err != nil {
+		utils.LogAction(userID.Hex(), "delete_report", "failure", "failed to cancel scan: "+err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to delete report"})
+		return
+	}
+	// The rendered DOM is stored on the report and goes with it.
 	err = services.DeleteReportByID(c.Request.Context(), reportID)
 	if err != nil {
 		utils.LogAction(userID.Hex(), "delete_report", "failure", err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to delete report"})
 		return
 	}
+	if err := services.DeleteBaselinesForReport(c.Request.Context(), reportID); err != nil {
+		utils.LogAction(userID.Hex(), "delete_report", "failure", "failed to unpin baseline: "+err.Error())
+	}
+	if err := services.DeleteScreenshotsForReport(c.Request.Context(), reportID); err != nil {
+		utils.LogAction(userID.Hex(), "delete_report", "failure", "failed to delete screenshots: "+err.Error())
+	}
+	if err := services.DeleteSuggestionsForReport(c.Request.Context(), reportID); err != nil {
+		utils.LogAction(userID.Hex(), "delete_report", "failure", "failed to delete suggestions: "+err.Error())
+	}
+	if report.CrawlID != nil {
+		// The page may have been the last one the crawl was waiting for.
+		if err := services.FinishCrawlIfDone(c.Request.Context(), *report.CrawlID); err != nil {
+			utils.LogAction(userID.Hex(), "delete_report", "failure", "failed to update crawl: "+err.Error())
+		}
+	}
 	utils.LogAction(userID.Hex(), "delete_report", "success", "deleted report "+reportID.Hex())
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Report deleted."})
 }
 
+func CancelReportHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	reportID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid report id"})
+		return
+	}
+	report, err := services.GetReportByID(c.Request.Context(), reportID)
+	if err != nil || report.UserID != userID {
+		utils.LogAction(userID.Hex(), "cancel_report", "failure", "not found or forbidden")
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Report not found"})
+		return
+	}
+	err = jobs.CancelAnalyzeJob(c.Request.Context(), reportID)
+	if errors.Is(err, jobs.ErrNotCancellable) {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": "Report has already finished", "data": gin.H{"status": report.Status}})
+		return
+	}
+	if err != nil {
+		utils.LogAction(userID.Hex(), "cancel_report", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to cancel report"})
+		return
+	}
+	utils.LogAction(userID.Hex(), "cancel_report", "success", "cancelled report "+reportID.Hex())
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Report cancelled.", "data": gin.H{"status": models.ReportStatusCancelled}})
+}
+
 func GetSuggestionsHandler(c *gin.Context) {
 	userID, ok := getUserIDFromClaims(c)
 	if !ok {
@@ -113,11 +201,313 @@ func GetSuggestionsHandler(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Report not found"})
 		return
 	}
+	// Contrast fixes are computed from the results, so they are there even
+	// when the LLM produced no suggestions.
+	fixes := services.ContrastFixes(report.AnalysisResults, c.Query("apca") == "true")
 	suggestions, err := services.GetSuggestionsByReportID(c.Request.Context(), reportID)
+	if errors.Is(err, mongo.ErrNoDocuments) && len(fixes) > 0 {
+		suggestions, err = map[string]interface{}{"reportId": reportID, "suggestions": []models.SuggestionItem{}}, nil
+	}
 	if err != nil {
 		utils.LogAction(userID.Hex(), "get_suggestions", "failure", err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch suggestions"})
 		return
 	}
+	suggestions["contrast"] = fixes
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": suggestions})
 }
+
+// ReportEventsHandler streams a report's status transitions over Server-Sent
+// Events: a "status" event per transition and a final "summary" event once the
+// report has finished, after which the stream ends.
+func ReportEventsHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	reportID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid report id"})
+		return
+	}
+	report, err := services.GetReportByID(c.Request.Context(), reportID)
+	if err != nil || report.UserID != userID {
+		utils.LogAction(userID.Hex(), "report_events", "failure", "not found or forbidden")
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Report not found"})
+		return
+	}
+	events, unsubscribe := jobs.SubscribeReport(reportID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	last := jobs.NewReportEvent(report)
+	send := func(ev jobs.ReportEvent) bool {
+		last = ev
+		c.SSEvent("status", ev)
+		if ev.Status.IsFinal() {
+			c.SSEvent("summary", ev.Summary)
+			return false
+		}
+		return true
+	}
+	if !send(last) {
+		c.Writer.Flush()
+		return
+	}
+	c.Writer.Flush()
+
+	// Events only come from workers in this process, so reload the report
+	// now and then to catch scans running elsewhere; this doubles as a
+	// keep-alive for idle streams.
+	refresh := time.NewTicker(5 * time.Second)
+	defer refresh.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case ev, ok := <-events:
+			if !ok {
+				return false
+			}
+			if ev.Status == last.Status && ev.Progress == last.Progress {
+				return true
+			}
+			return send(ev)
+		case <-refresh.C:
+			report, err := services.GetReportByID(c.Request.Context(), reportID)
+			if err != nil {
+				return false
+			}
+			if report.Status != last.Status {
+				return send(jobs.NewReportEvent(report))
+			}
+			_, _ = io.WriteString(w, ": keep-alive\n\n")
+			return true
+		}
+	})
+}
+
+// DiffReportsHandler compares report :id with report :otherId and lists the
+// violations :otherId introduced, resolved and kept relative to :id.
+func DiffReportsHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	baseID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid report id"})
+		return
+	}
+	otherID, err := primitive.ObjectIDFromHex(c.Param("otherId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid report id"})
+		return
+	}
+	var reports [2]*models.Report
+	for i, id := range []primitive.ObjectID{baseID, otherID} {
+		report, err := services.GetReportByID(c.Request.Context(), id)
+		if err != nil || report.UserID != userID {
+			utils.LogAction(userID.Hex(), "diff_reports", "failure", "not found or forbidden")
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Report not found"})
+			return
+		}
+		if report.AnalysisResults == nil {
+			c.JSON(http.StatusConflict, gin.H{"success": false, "message": "Report has no results yet", "data": gin.H{"reportId": id, "status": report.Status}})
+			return
+		}
+		reports[i] = report
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": services.DiffReports(reports[0], reports[1])})
+}
+
+func GetScreenshotHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	reportID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid report id"})
+		return
+	}
+	imageID, err := primitive.ObjectIDFromHex(c.Param("imageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid image id"})
+		return
+	}
+	report, err := services.GetReportByID(c.Request.Context(), reportID)
+	if err != nil || report.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Report not found"})
+		return
+	}
+	stream, contentType, err := services.OpenScreenshot(c.Request.Context(), reportID, imageID)
+	if errors.Is(err, services.ErrScreenshotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Screenshot not found"})
+		return
+	}
+	if err != nil {
+		utils.LogAction(userID.Hex(), "get_screenshot", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch screenshot"})
+		return
+	}
+	defer stream.Close()
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.DataFromReader(http.StatusOK, stream.GetFile().Length, contentType, stream, nil)
+}
+
+// GetReportDOMHandler returns the page a report scanned: the rendered page of
+// a URL scan or the HTML that was submitted.
+func GetReportDOMHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	reportID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid report id"})
+		return
+	}
+	report, err := services.GetReportByID(c.Request.Context(), reportID)
+	if err != nil || report.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Report not found"})
+		return
+	}
+	// A rescan scanned the page stored on another report.
+	if report.RescanOf != nil {
+		if source, err := services.GetReportByID(c.Request.Context(), *report.RescanOf); err == nil {
+			report = source
+		}
+	}
+	// The page is someone else's markup: never run its scripts or let the
+	// browser treat it as anything but the HTML it is labelled as.
+	c.Header("Content-Security-Policy", "sandbox")
+	c.Header("X-Content-Type-Options", "nosniff")
+	if report.HTMLSnapshot != "" {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(report.HTMLSnapshot))
+		return
+	}
+	compressed, err := services.GetReportDOM(c.Request.Context(), report.ID)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "get_report_dom", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch page snapshot"})
+		return
+	}
+	if compressed == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Report has no page snapshot"})
+		return
+	}
+	// Clients that accept gzip get the stored bytes as they are.
+	c.Header("Vary", "Accept-Encoding")
+	if acceptsGzip(c.GetHeader("Accept-Encoding")) {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, "text/html; charset=utf-8", compressed)
+		return
+	}
+	dom, err := services.ReadReportDOM(compressed)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "get_report_dom", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to read page snapshot"})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(dom))
+}
+
+// acceptsGzip reads an Accept-Encoding header. gzip is acceptable when it is
+// listed, or covered by "*", with a q-value above zero.
+func acceptsGzip(header string) bool {
+	gzip, star := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(strings.TrimSpace(name), "q") {
+				v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					v = 0
+				}
+				q = v
+			}
+		}
+		switch coding {
+		case "gzip", "x-gzip":
+			gzip = q
+		case "*":
+			star = q
+		}
+	}
+	if gzip >= 0 {
+		return gzip > 0
+	}
+	return star > 0
+}
+
+// RescanReportHandler scans the page a report stored again, without fetching
+// the site, and returns the new report.
+func RescanReportHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	reportID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid report id"})
+		return
+	}
+	source, err := services.GetReportByID(c.Request.Context(), reportID)
+	if err != nil || source.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Report not found"})
+		return
+	}
+	// A rescan reads its page from the report that stored it, so rescanning
+	// a rescan goes back to that report.
+	pageID := source.ID
+	if source.RescanOf != nil {
+		pageID = *source.RescanOf
+	} else if source.HTMLSnapshot == "" && source.RenderedDOMSize == 0 {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": "Report has no stored page to rescan"})
+		return
+	}
+	report := services.NewReport(userID, source.URL, "")
+	report.RescanOf = &pageID
+	report.ScanOptions = source.ScanOptions
+	report.Device = source.Device
+	report.Engine = source.Engine
+	report, err = services.InsertReport(c.Request.Context(), report)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "rescan_report", "failure", "failed to create report")
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to create report"})
+		return
+	}
+	err = jobs.EnqueueAnalyzeJob(c.Request.Context(), jobs.AnalyzeJob{
+		ReportID: report.ID,
+		UserID:   userID,
+		RescanOf: report.RescanOf,
+		Options:  report.ScanOptions,
+		Device:   report.Device,
+		Engine:   report.Engine,
+	})
+	if err != nil {
+		utils.LogAction(userID.Hex(), "rescan_report", "failure", "failed to enqueue job: "+err.Error())
+		_ = services.FailReport(c.Request.Context(), report.ID, "Failed to enqueue job")
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to queue analysis"})
+		return
+	}
+	utils.LogAction(userID.Hex(), "rescan_report", "success", "rescanning report "+reportID.Hex()+" as "+report.ID.Hex())
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Rescan started",
+		"data":    gin.H{"reportId": report.ID, "rescanOf": source.ID, "status": report.Status, "createdAt": report.CreatedAt},
+	})
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// minScheduleInterval is the shortest gap allowed between two runs of a
+// schedule.
+var minScheduleInterval = utils.EnvDuration("SCHEDULE_MIN_INTERVAL", 5*time.Minute)
+
+// scheduleGapRuns is how many upcoming runs are checked against
+// minScheduleInterval. It covers several days of a schedule that runs every
+// few minutes and years of one that runs a few times a month.
+const scheduleGapRuns = 2000
+
+func RegisterScheduleRoutes(router *gin.Engine) {
+	schedules := router.Group("/api/schedules")
+	schedules.Use(AuthMiddleware())
+	{
+		schedules.POST("", CreateScheduleHandler)
+		schedules.GET("", ListSchedulesHandler)
+		schedules.GET(":id", GetScheduleHandler)
+		schedules.PUT(":id", UpdateScheduleHandler)
+		schedules.DELETE(":id", DeleteScheduleHandler)
+	}
+}
+
+// scheduleRequest is the body of POST and PUT /api/schedules. It needs either
+// url or crawl.
+type scheduleRequest struct {
+	Name            string                 `json:"name"`
+	Cron            string                 `json:"cron"`
+	Timezone        string                 `json:"timezone"`
+	URL             string                 `json:"url"`
+	Crawl           *crawlRequest          `json:"crawl"`
+	Enabled         *bool                  `json:"enabled"`
+	MissedRunPolicy models.MissedRunPolicy `json:"missedRunPolicy"`
+}
+
+// apply validates the request and copies it onto schedule. Its errors are
+// meant for the caller.
+func (r scheduleRequest) apply(schedule *models.Schedule) error {
+	if r.Cron == "" {
+		return errors.New("cron is required")
+	}
+	gap, err := services.ScheduleMinGap(r.Cron, r.Timezone, time.Now(), scheduleGapRuns)
+	if err != nil {
+		return err
+	}
+	if gap < minScheduleInterval {
+		return errors.New("schedule may run at most once every " + minScheduleInterval.String())
+	}
+	switch {
+	case r.URL != "" && r.Crawl != nil:
+		return errors.New("url and crawl cannot both be set")
+	case r.URL != "":
+		u, _, err := services.NormalizeURL(r.URL)
+		if err != nil {
+			return errors.New("url must be an http or https URL")
+		}
+		schedule.URL, schedule.Crawl = u, nil
+	case r.Crawl != nil:
+		cfg, err := r.Crawl.config()
+		if err != nil {
+			return err
+		}
+		schedule.URL, schedule.Crawl = "", &cfg
+	default:
+		return errors.New("url or crawl is required")
+	}
+	switch r.MissedRunPolicy {
+	case "":
+		schedule.MissedRunPolicy = models.MissedRunOnce
+	case models.MissedRunOnce, models.MissedRunSkip:
+		schedule.MissedRunPolicy = r.MissedRunPolicy
+	default:
+		return errors.New("missedRunPolicy must be run_once or skip")
+	}
+	schedule.Name = r.Name
+	schedule.Cron = r.Cron
+	schedule.Timezone = r.Timezone
+	schedule.Enabled = r.Enabled == nil || *r.Enabled
+	return nil
+}
+
+func CreateScheduleHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	var req scheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+		return
+	}
+	schedule := &models.Schedule{UserID: userID}
+	if err := req.apply(schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err := services.CreateSchedule(c.Request.Context(), schedule); err != nil {
+		utils.LogAction(userID.Hex(), "create_schedule", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to create schedule"})
+		return
+	}
+	utils.LogAction(userID.Hex(), "create_schedule", "success", "created schedule "+schedule.ID.Hex())
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": schedule})
+}
+
+func ListSchedulesHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	schedules, err := services.ListSchedulesByUser(c.Request.Context(), userID)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "list_schedules", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to fetch schedules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": schedules})
+}
+
+func GetScheduleHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	scheduleID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid schedule id"})
+		return
+	}
+	schedule, err := services.GetScheduleByID(c.Request.Context(), scheduleID)
+	if err != nil || schedule.UserID != userID {
+		utils.LogAction(userID.Hex(), "get_schedule", "failure", "not found or forbidden")
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Schedule not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": schedule})
+}
+
+// UpdateScheduleHandler replaces a schedule's settings. Its next run is
+// worked out again from now.
+func UpdateScheduleHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	scheduleID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid schedule id"})
+		return
+	}
+	var req scheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request body"})
+		return
+	}
+	schedule, err := services.GetScheduleByID(c.Request.Context(), scheduleID)
+	if err != nil || schedule.UserID != userID {
+		utils.LogAction(userID.Hex(), "update_schedule", "failure", "not found or forbidden")
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Schedule not found"})
+		return
+	}
+	if err := req.apply(schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	err = services.UpdateSchedule(c.Request.Context(), schedule)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Schedule not found"})
+		return
+	}
+	if err != nil {
+		utils.LogAction(userID.Hex(), "update_schedule", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to update schedule"})
+		return
+	}
+	utils.LogAction(userID.Hex(), "update_schedule", "success", "updated schedule "+scheduleID.Hex())
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": schedule})
+}
+
+func DeleteScheduleHandler(c *gin.Context) {
+	userID, ok := getUserIDFromClaims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Unauthorized"})
+		return
+	}
+	scheduleID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid schedule id"})
+		return
+	}
+	deleted, err := services.DeleteSchedule(c.Request.Context(), scheduleID, userID)
+	if err != nil {
+		utils.LogAction(userID.Hex(), "delete_schedule", "failure", err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to delete schedule"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Schedule not found"})
+		return
+	}
+	utils.LogAction(userID.Hex(), "delete_schedule", "success", "deleted schedule "+scheduleID.Hex())
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Schedule deleted."})
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"backend/models"
+)
+
+func TestScheduleRequestMinInterval(t *testing.T) {
+	tests := []struct {
+		cron    string
+		wantErr bool
+	}{
+		{"*/5 * * * *", false},
+		{"@hourly", false},
+		{"* * * * *", true},
+		{"*/2 * * * *", true},
+		// Fires twice in a row a minute apart, then not for an hour.
+		{"0,1 * * * *", true},
+		{"58,59 */6 * * *", true},
+		// 23:59 to 00:00 is the only short gap.
+		{"0,59 0,23 * * *", true},
+	}
+	for _, tt := range tests {
+		req := scheduleRequest{Cron: tt.cron, URL: "https://example.com"}
+		err := req.apply(&models.Schedule{})
+		if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "at most once every")) {
+			t.Errorf("apply(%q) error = %v, want the minimum interval error", tt.cron, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("apply(%q) error = %v", tt.cron, err)
+		}
+	}
+}
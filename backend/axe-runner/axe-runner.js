@@ -1,26 +1,186 @@
 const puppeteer = require('puppeteer');
 const axeCore = require('axe-core');
+const { highlightClip } = require('./highlight');
 
-async function runAxe({ url, html }) {
+const NAVIGATION_TIMEOUT = 10000;
+const LOGIN_STEP_TIMEOUT = 10000;
+
+// Applies cookies, headers and basic auth, then runs the scripted login steps
+// in order. Everything is applied to the page itself, so callers that reuse
+// pages must not hand an authenticated page to another scan.
+async function applyAuth(page, url, auth) {
+  if (auth.headers) await page.setExtraHTTPHeaders(auth.headers);
+  if (auth.basicAuth) await page.authenticate(auth.basicAuth);
+  if (auth.cookies && auth.cookies.length) {
+    await page.setCookie(...auth.cookies.map(c => (c.domain ? c : { ...c, url })));
+  }
+  for (const [i, step] of (auth.loginSteps || []).entries()) {
+    const timeout = step.timeoutMs || LOGIN_STEP_TIMEOUT;
+    try {
+      switch (step.action) {
+        case 'goto':
+          await page.goto(step.url, { waitUntil: 'domcontentloaded', timeout });
+          break;
+        case 'fill':
+          await page.waitForSelector(step.selector, { timeout });
+          await page.$eval(step.selector, el => { el.value = ''; });
+          await page.type(step.selector, step.value || '');
+          break;
+        case 'click':
+          await page.waitForSelector(step.selector, { timeout });
+          await page.click(step.selector);
+          break;
+        case 'waitForSelector':
+          await page.waitForSelector(step.selector, { timeout });
+          break;
+        case 'waitForNavigation':
+          await page.waitForNavigation({ waitUntil: 'domcontentloaded', timeout });
+          break;
+        default:
+          throw new Error(`unknown action ${step.action}`);
+      }
+    } catch (e) {
+      // Step values may be passwords, so only the action and selector are
+      // reported.
+      throw new Error(`Login step ${i + 1} (${step.action}${step.selector ? ' ' + step.selector : ''}) failed: ${e.message}`);
+    }
+  }
+}
+
+// Puppeteer's own viewport, used when a scan does not ask for a device.
+const DEFAULT_VIEWPORT = { width: 800, height: 600 };
+
+// Sets the viewport and user agent for a scan. Pooled pages keep both between
+// scans, so they are always set, falling back to the defaults.
+async function applyDevice(page, device) {
+  if (device) {
+    await page.setViewport({
+      width: device.width,
+      height: device.height,
+      deviceScaleFactor: device.deviceScaleFactor || 1,
+      isMobile: !!device.isMobile,
+      hasTouch: !!device.hasTouch,
+    });
+  } else {
+    await page.setViewport(DEFAULT_VIEWPORT);
+  }
+  const userAgent = (device && device.userAgent) || await page.browser().userAgent();
+  await page.setUserAgent(userAgent);
+}
+
+// Serializes the rendered page so it can be scanned again later without the
+// site: scripts are dropped so they do not run a second time, and a <base> is
+// added so relative stylesheets and images still resolve.
+async function renderedDOM(page) {
+  return await page.evaluate(() => {
+    const root = document.documentElement.cloneNode(true);
+    root.querySelectorAll('script').forEach(el => el.remove());
+    const head = root.querySelector('head');
+    if (head && !root.querySelector('base[href]')) {
+      const base = document.createElement('base');
+      base.href = document.baseURI;
+      head.prepend(base);
+    }
+    const doctype = document.doctype ? new XMLSerializer().serializeToString(document.doctype) + '\n' : '';
+    return doctype + root.outerHTML;
+  });
+}
+
+// Takes a full-page JPEG and a PNG crop of each violating element with the
+// element outlined. Images are base64 encoded; crops refer to their node by
+// rule id and position in that rule's nodes. Elements inside iframes or shadow
+// DOM, hidden elements and those whose selector no longer matches are skipped.
+async function captureScreenshots(page, results, { maxNodes = 0 } = {}) {
+  const shots = { page: null, nodes: [] };
+  try {
+    shots.page = await page.screenshot({ fullPage: true, type: 'jpeg', quality: 70, encoding: 'base64' });
+  } catch {}
+  for (const rule of results.violations || []) {
+    for (const [index, node] of rule.nodes.entries()) {
+      if (shots.nodes.length >= maxNodes) return shots;
+      if (node.target.length !== 1 || typeof node.target[0] !== 'string') continue;
+      const handle = await page.$(node.target[0]).catch(() => null);
+      if (!handle) continue;
+      let previous;
+      try {
+        // Scrolling first lets lazily rendered content appear; the clip is
+        // in document coordinates, so it does not depend on where we end up.
+        const geometry = await handle.evaluate(el => {
+          el.scrollIntoView({ block: 'center', inline: 'center' });
+          const r = el.getBoundingClientRect();
+          const root = document.documentElement;
+          return {
+            rect: { x: r.x, y: r.y, width: r.width, height: r.height },
+            scroll: { x: window.scrollX, y: window.scrollY },
+            doc: { width: Math.max(root.scrollWidth, root.clientWidth), height: Math.max(root.scrollHeight, root.clientHeight) },
+          };
+        });
+        const clip = highlightClip(geometry.rect, geometry.scroll, geometry.doc);
+        if (!clip) continue;
+        previous = await handle.evaluate(el => {
+          const outline = el.style.outline;
+          el.style.outline = '3px solid #e11d48';
+          el.style.outlineOffset = '2px';
+          return outline;
+        });
+        const image = await page.screenshot({ type: 'png', encoding: 'base64', clip, captureBeyondViewport: true });
+        shots.nodes.push({ rule: rule.id, node: index, image });
+      } catch {
+        // A node that cannot be captured just goes without an image.
+      } finally {
+        if (previous !== undefined) {
+          await handle.evaluate((el, outline) => {
+            el.style.outline = outline;
+            el.style.outlineOffset = '';
+          }, previous).catch(() => {});
+        }
+        await handle.dispose().catch(() => {});
+      }
+    }
+  }
+  return shots;
+}
+
+// Loads the requested page into an already open tab and runs axe against it.
+// onPhase is told when the scan starts fetching the page and when axe starts.
+async function scanPage(page, { url, html, auth, axe = {}, device, screenshots, snapshot }, onPhase = () => {}) {
+  await applyDevice(page, device);
+  if (url) {
+    onPhase('fetching');
+    if (auth) await applyAuth(page, url, auth);
+    await page.goto(url, { waitUntil: 'domcontentloaded', timeout: NAVIGATION_TIMEOUT });
+  } else if (html) {
+    await page.setContent(html, { waitUntil: 'domcontentloaded', timeout: NAVIGATION_TIMEOUT });
+  } else {
+    throw new Error('Must provide url or html');
+  }
+  onPhase('scanning');
+  // Taken before axe is injected so the snapshot is the page alone.
+  const dom = snapshot ? await renderedDOM(page) : null;
+  await page.addScriptTag({ content: axeCore.source });
+  // axe.context and axe.options are passed straight to axe.run(); the
+  // backend builds them from the report's scan options.
+  const results = await page.evaluate(async (context, options) => {
+    return await window.axe.run(context || document, options || {});
+  }, axe.context, axe.options);
+  if (screenshots) {
+    results.screenshots = await captureScreenshots(page, results, screenshots);
+  }
+  if (dom !== null) results.dom = dom;
+  return results;
+}
+
+async function runAxe(params) {
   const browser = await puppeteer.launch({headless: "new", args: ['--no-sandbox'] });
   const page = await browser.newPage();
   try {
-    if (url) {
-      await page.goto(url, { waitUntil: 'domcontentloaded', timeout: 10000 });
-    } else if (html) {
-      await page.setContent(html, { waitUntil: 'domcontentloaded', timeout: 10000 });
-    } else {
-      throw new Error('Must provide url or html');
-    }
-    await page.addScriptTag({ content: axeCore.source });
-    const results = await page.evaluate(async () => {
-      return await window.axe.run();
-    });
+    // Phases go to stderr so stdout stays a single JSON document.
+    const results = await scanPage(page, params, phase => console.error(`phase:${phase}`));
     await browser.close();
     return results;
   } catch (e) {
     await browser.close();
-    return { error: true, message: e.message, url };
+    return { error: true, message: e.message, url: params.url };
   }
 }
 
@@ -46,4 +206,8 @@ async function main() {
   }
 }
 
-main();
+if (require.main === module) {
+  main();
+}
+
+module.exports = { scanPage, captureScreenshots };
@@ -0,0 +1,20 @@
+// Padding kept around an element in its highlight crop, in CSS pixels.
+const HIGHLIGHT_PADDING = 16;
+
+// Returns the page.screenshot() clip for an element, or null when nothing of
+// it is on the page. rect is the element's getBoundingClientRect(), which is
+// relative to the viewport, while the clip is relative to the document, so
+// the scroll offsets are added. The clip is bounded by the document's size,
+// not the viewport's, so elements below the fold are captured whole.
+function highlightClip(rect, scroll, doc) {
+  const left = rect.x + scroll.x;
+  const top = rect.y + scroll.y;
+  const x = Math.max(0, left - HIGHLIGHT_PADDING);
+  const y = Math.max(0, top - HIGHLIGHT_PADDING);
+  const right = Math.min(doc.width, left + rect.width + HIGHLIGHT_PADDING);
+  const bottom = Math.min(doc.height, top + rect.height + HIGHLIGHT_PADDING);
+  if (rect.width < 1 || rect.height < 1 || right - x < 1 || bottom - y < 1) return null;
+  return { x, y, width: right - x, height: bottom - y };
+}
+
+module.exports = { HIGHLIGHT_PADDING, highlightClip };
@@ -0,0 +1,205 @@
+// server.js
+// Usage: node server.js
+// Long-lived axe runner: keeps one Chromium and a pool of open pages, and
+// scans whatever is POSTed to /analyze. GET /healthz reports pool usage.
+
+const http = require('http');
+const puppeteer = require('puppeteer');
+const { scanPage } = require('./axe-runner');
+
+const PORT = Number(process.env.PORT || 3001);
+const POOL_SIZE = Number(process.env.POOL_SIZE || 4);
+const PAGE_MAX_USES = Number(process.env.PAGE_MAX_USES || 50);
+const MAX_BODY_BYTES = 10 * 1024 * 1024;
+
+let browser = null;
+let launching = null;
+const idle = [];
+const waiting = [];
+let busy = 0;
+
+async function getBrowser() {
+  if (browser && browser.isConnected()) return browser;
+  if (!launching) {
+    launching = puppeteer.launch({ headless: 'new', args: ['--no-sandbox', '--disable-setuid-sandbox'] })
+      .then(b => {
+        browser = b;
+        idle.length = 0;
+        b.on('disconnected', () => {
+          console.error('Browser disconnected, will relaunch on next scan');
+          browser = null;
+        });
+        return b;
+      })
+      .finally(() => { launching = null; });
+  }
+  return launching;
+}
+
+async function newSlot() {
+  const b = await getBrowser();
+  const context = await b.createIncognitoBrowserContext();
+  const page = await context.newPage();
+  return { context, page, uses: 0 };
+}
+
+async function acquire() {
+  if (busy >= POOL_SIZE) {
+    // release() hands its place straight to us without lowering busy, so no
+    // new caller can slip in while we wake up.
+    await new Promise(resolve => waiting.push(resolve));
+  } else {
+    busy++;
+  }
+  try {
+    const slot = idle.pop();
+    if (slot && !slot.page.isClosed()) return slot;
+    return await newSlot();
+  } catch (e) {
+    release(null);
+    throw e;
+  }
+}
+
+async function discard(slot) {
+  try {
+    await slot.context.close();
+  } catch {}
+}
+
+function release(slot) {
+  if (slot) idle.push(slot);
+  const next = waiting.shift();
+  if (next) {
+    next();
+  } else {
+    busy--;
+  }
+}
+
+// Resets a page between scans; pages that misbehave or have been used too
+// often are closed so their memory is given back.
+async function recycle(slot, healthy) {
+  slot.uses++;
+  if (!healthy || slot.uses >= PAGE_MAX_USES || !browser) {
+    await discard(slot);
+    release(null);
+    return;
+  }
+  try {
+    await slot.page.goto('about:blank');
+    release(slot);
+  } catch {
+    await discard(slot);
+    release(null);
+  }
+}
+
+// onAbort is called with a function that tears the page down, so a scan whose
+// caller has gone away (timeout or cancellation) stops instead of running on.
+async function analyze(params, onAbort, onPhase) {
+  const slot = await acquire();
+  let healthy = true;
+  onAbort(() => {
+    healthy = false;
+    slot.context.close().catch(() => {});
+  });
+  try {
+    return await scanPage(slot.page, params, onPhase);
+  } catch (e) {
+    healthy = false;
+    return { error: true, message: e.message, url: params.url };
+  } finally {
+    // Cookies, headers and logins stay with the page's context, so a page
+    // used for an authenticated scan is never handed to another one.
+    await recycle(slot, healthy && !params.auth);
+  }
+}
+
+function readBody(req) {
+  return new Promise((resolve, reject) => {
+    let size = 0;
+    const chunks = [];
+    req.on('data', chunk => {
+      size += chunk.length;
+      if (size > MAX_BODY_BYTES) {
+        reject(new Error('Request body too large'));
+        req.destroy();
+        return;
+      }
+      chunks.push(chunk);
+    });
+    req.on('end', () => resolve(Buffer.concat(chunks).toString('utf8')));
+    req.on('error', reject);
+  });
+}
+
+function send(res, status, body) {
+  res.writeHead(status, { 'Content-Type': 'application/json' });
+  res.end(JSON.stringify(body));
+}
+
+const server = http.createServer(async (req, res) => {
+  if (req.method === 'GET' && req.url === '/healthz') {
+    send(res, 200, {
+      status: 'ok',
+      browser: Boolean(browser && browser.isConnected()),
+      pool: { size: POOL_SIZE, busy, idle: idle.length, waiting: waiting.length },
+    });
+    return;
+  }
+  if (req.method !== 'POST' || req.url !== '/analyze') {
+    send(res, 404, { error: true, message: 'Not found' });
+    return;
+  }
+  let params;
+  try {
+    params = JSON.parse(await readBody(req));
+  } catch (e) {
+    send(res, 400, { error: true, message: 'Invalid JSON input' });
+    return;
+  }
+  let abort = null;
+  res.on('close', () => {
+    if (!res.writableEnded && abort) abort();
+  });
+  const onAbort = fn => { abort = fn; };
+  // Clients that accept NDJSON get {"phase"} lines while the scan runs and a
+  // final {"result"} line; everyone else gets the plain result.
+  if ((req.headers.accept || '').includes('application/x-ndjson')) {
+    res.writeHead(200, { 'Content-Type': 'application/x-ndjson' });
+    const line = msg => res.write(JSON.stringify(msg) + '\n');
+    try {
+      line({ result: await analyze(params, onAbort, phase => line({ phase })) });
+    } catch (e) {
+      line({ result: { error: true, message: e.message, url: params.url } });
+    }
+    res.end();
+    return;
+  }
+  try {
+    send(res, 200, await analyze(params, onAbort));
+  } catch (e) {
+    send(res, 500, { error: true, message: e.message });
+  }
+});
+
+async function shutdown() {
+  server.close();
+  if (browser) await browser.close();
+  process.exit(0);
+}
+
+process.on('SIGTERM', shutdown);
+process.on('SIGINT', shutdown);
+
+getBrowser()
+  .then(() => {
+    server.listen(PORT, () => {
+      console.log(`axe-runner service listening on :${PORT} with ${POOL_SIZE} pages`);
+    });
+  })
+  .catch(e => {
+    console.error('Failed to launch browser:', e.message);
+    process.exit(1);
+  });
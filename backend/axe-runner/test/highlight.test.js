@@ -0,0 +1,32 @@
+const test = require('node:test');
+const assert = require('node:assert');
+const { HIGHLIGHT_PADDING: pad, highlightClip } = require('../highlight');
+
+const doc = { width: 800, height: 5000 };
+
+test('element in view is clipped where it is', () => {
+  const clip = highlightClip({ x: 100, y: 50, width: 200, height: 40 }, { x: 0, y: 0 }, doc);
+  assert.deepStrictEqual(clip, { x: 100 - pad, y: 50 - pad, width: 200 + 2 * pad, height: 40 + 2 * pad });
+});
+
+test('element below the fold is clipped in document coordinates', () => {
+  // Scrolled so the element at document y=3000 sits in the middle of a
+  // 600px viewport.
+  const clip = highlightClip({ x: 100, y: 280, width: 200, height: 40 }, { x: 0, y: 2720 }, doc);
+  assert.deepStrictEqual(clip, { x: 100 - pad, y: 3000 - pad, width: 200 + 2 * pad, height: 40 + 2 * pad });
+});
+
+test('element taller than the viewport is not cut to the viewport', () => {
+  const clip = highlightClip({ x: 0, y: -300, width: 800, height: 1200 }, { x: 0, y: 1000 }, doc);
+  assert.deepStrictEqual(clip, { x: 0, y: 700 - pad, width: 800, height: 1200 + 2 * pad });
+});
+
+test('clip stays inside the document', () => {
+  const clip = highlightClip({ x: 790, y: 4990, width: 20, height: 20 }, { x: 0, y: 0 }, doc);
+  assert.deepStrictEqual(clip, { x: 790 - pad, y: 4990 - pad, width: 10 + pad, height: 10 + pad });
+});
+
+test('empty or off-page elements have no clip', () => {
+  assert.strictEqual(highlightClip({ x: 10, y: 10, width: 0, height: 20 }, { x: 0, y: 0 }, doc), null);
+  assert.strictEqual(highlightClip({ x: 900, y: 10, width: 50, height: 20 }, { x: 0, y: 0 }, doc), null);
+});
@@ -0,0 +1,52 @@
+// Needs puppeteer and a Chromium it can launch (run `npm install` first, or
+// set PUPPETEER_EXECUTABLE_PATH); skipped otherwise.
+const test = require('node:test');
+const assert = require('node:assert');
+
+let puppeteer;
+try {
+  puppeteer = require('puppeteer');
+} catch {}
+
+// Reads the width and height from a PNG's IHDR chunk.
+function pngSize(base64) {
+  const png = Buffer.from(base64, 'base64');
+  return { width: png.readUInt32BE(16), height: png.readUInt32BE(20) };
+}
+
+test('crops an element below the fold', { skip: !puppeteer && 'puppeteer is not installed' }, async () => {
+  const { captureScreenshots } = require('../axe-runner');
+  const { HIGHLIGHT_PADDING: pad } = require('../highlight');
+  const browser = await puppeteer.launch({ headless: 'new', args: ['--no-sandbox'] });
+  try {
+    const page = await browser.newPage();
+    await page.setViewport({ width: 800, height: 600 });
+    await page.setContent(`<!DOCTYPE html>
+      <body style="margin:0">
+        <div style="height:3000px"></div>
+        <img id="late" src="data:," style="display:block;margin-left:100px;width:200px;height:100px;background:#1d4ed8">
+        <div style="height:2000px"></div>
+      </body>`);
+    const results = { violations: [{ id: 'image-alt', nodes: [{ target: ['#late'] }] }] };
+    const shots = await captureScreenshots(page, results, { maxNodes: 5 });
+    assert.strictEqual(shots.nodes.length, 1);
+    assert.deepStrictEqual(pngSize(shots.nodes[0].image), { width: 200 + 2 * pad, height: 100 + 2 * pad });
+
+    // The middle of the crop is the element itself, not whatever the
+    // viewport showed before scrolling.
+    const centre = await page.evaluate(async src => {
+      const img = new Image();
+      img.src = 'data:image/png;base64,' + src;
+      await img.decode();
+      const canvas = document.createElement('canvas');
+      canvas.width = img.width;
+      canvas.height = img.height;
+      const ctx = canvas.getContext('2d');
+      ctx.drawImage(img, 0, 0);
+      return Array.from(ctx.getImageData(img.width / 2, img.height / 2, 1, 1).data.slice(0, 3));
+    }, shots.nodes[0].image);
+    assert.deepStrictEqual(centre, [29, 78, 216]);
+  } finally {
+    await browser.close();
+  }
+});
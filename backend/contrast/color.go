@@ -0,0 +1,126 @@
+// Package contrast computes the contrast between text and background colors
+// the way WCAG 2.x and APCA define it, and finds the closest colors to a
+// failing pair that would pass.
+package contrast
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Color is an sRGB color with an alpha channel between 0 and 1.
+type Color struct {
+	R, G, B uint8
+	A       float64
+}
+
+// Parse reads a color in the forms axe reports them: #rgb, #rgba, #rrggbb,
+// #rrggbbaa, rgb() and rgba().
+func Parse(s string) (Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(s, "#"):
+		return parseHex(s[1:])
+	case strings.HasPrefix(s, "rgb(") || strings.HasPrefix(s, "rgba("):
+		return parseRGB(s[strings.IndexByte(s, '(')+1:])
+	}
+	return Color{}, fmt.Errorf("unsupported color %q", s)
+}
+
+func parseHex(h string) (Color, error) {
+	if len(h) == 3 || len(h) == 4 {
+		var long strings.Builder
+		for _, c := range h {
+			long.WriteRune(c)
+			long.WriteRune(c)
+		}
+		h = long.String()
+	}
+	if len(h) != 6 && len(h) != 8 {
+		return Color{}, fmt.Errorf("invalid hex color #%s", h)
+	}
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return Color{}, fmt.Errorf("invalid hex color #%s", h)
+	}
+	c := Color{A: 1}
+	if len(h) == 8 {
+		c.A = float64(v&0xff) / 255
+		v >>= 8
+	}
+	c.R, c.G, c.B = uint8(v>>16), uint8(v>>8), uint8(v)
+	return c, nil
+}
+
+func parseRGB(args string) (Color, error) {
+	end := strings.IndexByte(args, ')')
+	if end < 0 {
+		return Color{}, fmt.Errorf("invalid rgb color")
+	}
+	parts := strings.FieldsFunc(args[:end], func(r rune) bool { return r == ',' || r == ' ' || r == '/' })
+	if len(parts) != 3 && len(parts) != 4 {
+		return Color{}, fmt.Errorf("invalid rgb color")
+	}
+	var channels [3]uint8
+	for i := 0; i < 3; i++ {
+		v, err := parseComponent(parts[i], 255)
+		if err != nil {
+			return Color{}, err
+		}
+		channels[i] = uint8(math.Round(v))
+	}
+	c := Color{R: channels[0], G: channels[1], B: channels[2], A: 1}
+	if len(parts) == 4 {
+		a, err := parseComponent(parts[3], 1)
+		if err != nil {
+			return Color{}, err
+		}
+		c.A = a
+	}
+	return c, nil
+}
+
+// parseComponent reads a number or percentage and clamps it to [0, max].
+func parseComponent(s string, max float64) (float64, error) {
+	scale := 1.0
+	if strings.HasSuffix(s, "%") {
+		s = strings.TrimSuffix(s, "%")
+		scale = max / 100
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid color component %q", s)
+	}
+	return math.Max(0, math.Min(max, v*scale)), nil
+}
+
+// Hex formats c as #rrggbb, dropping alpha.
+func (c Color) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Over composites c onto an opaque background.
+func (c Color) Over(bg Color) Color {
+	if c.A >= 1 {
+		return Color{R: c.R, G: c.G, B: c.B, A: 1}
+	}
+	mix := func(f, b uint8) uint8 {
+		return uint8(math.Round(float64(f)*c.A + float64(b)*(1-c.A)))
+	}
+	return Color{R: mix(c.R, bg.R), G: mix(c.G, bg.G), B: mix(c.B, bg.B), A: 1}
+}
+
+func linear(v uint8) float64 {
+	c := float64(v) / 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// Luminance is the WCAG 2.x relative luminance of c, ignoring alpha.
+func (c Color) Luminance() float64 {
+	return 0.2126*linear(c.R) + 0.7152*linear(c.G) + 0.0722*linear(c.B)
+}
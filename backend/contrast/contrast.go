@@ -0,0 +1,127 @@
+package contrast
+
+import "math"
+
+// Level is a WCAG conformance level for text contrast.
+type Level string
+
+const (
+	AA  Level = "AA"
+	AAA Level = "AAA"
+)
+
+// Ratio is the WCAG 2.x contrast ratio between two opaque colors, from 1 to 21.
+func Ratio(a, b Color) float64 {
+	la, lb := a.Luminance(), b.Luminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// IsLargeText reports whether text counts as large under WCAG: at least 18pt,
+// or 14pt when bold.
+func IsLargeText(sizePx float64, bold bool) bool {
+	if bold {
+		return sizePx >= 14*4.0/3
+	}
+	return sizePx >= 18*4.0/3
+}
+
+// Required is the minimum contrast ratio for text at a level.
+func Required(level Level, large bool) float64 {
+	switch {
+	case level == AAA && large:
+		return 4.5
+	case level == AAA:
+		return 7
+	case large:
+		return 3
+	}
+	return 4.5
+}
+
+// APCA returns the APCA-W3 (0.0.98G) lightness contrast Lc of text on an
+// opaque background. It is positive for dark text on a light background and
+// negative for light text on a dark one; readable body text needs about 75 or
+// more in magnitude.
+func APCA(text, bg Color) float64 {
+	const (
+		blkThrs   = 0.022
+		blkClmp   = 1.414
+		deltaYMin = 0.0005
+		scale     = 1.14
+		offset    = 0.027
+		loClip    = 0.1
+	)
+	y := func(c Color) float64 {
+		v := 0.2126729*math.Pow(float64(c.R)/255, 2.4) +
+			0.7151522*math.Pow(float64(c.G)/255, 2.4) +
+			0.0721750*math.Pow(float64(c.B)/255, 2.4)
+		if v < blkThrs {
+			v += math.Pow(blkThrs-v, blkClmp)
+		}
+		return v
+	}
+	yText, yBg := y(text), y(bg)
+	if math.Abs(yBg-yText) < deltaYMin {
+		return 0
+	}
+	var lc float64
+	if yBg > yText {
+		sapc := (math.Pow(yBg, 0.56) - math.Pow(yText, 0.57)) * scale
+		if sapc >= loClip {
+			lc = sapc - offset
+		}
+	} else {
+		sapc := (math.Pow(yBg, 0.65) - math.Pow(yText, 0.62)) * scale
+		if sapc <= -loClip {
+			lc = sapc + offset
+		}
+	}
+	return lc * 100
+}
+
+// Nearest returns the color closest to c whose contrast with other is at
+// least target. It mixes c toward both black and white and keeps whichever
+// passing color needs the smaller mix, so a light color on a light background
+// may come back darker. ok is false when neither direction reaches target.
+func Nearest(c, other Color, target float64) (Color, bool) {
+	if Ratio(c, other) >= target {
+		return c, true
+	}
+	dark, tDark, okDark := mixUntil(c, Color{A: 1}, other, target)
+	light, tLight, okLight := mixUntil(c, Color{R: 255, G: 255, B: 255, A: 1}, other, target)
+	switch {
+	case okDark && (!okLight || tDark <= tLight):
+		return dark, true
+	case okLight:
+		return light, true
+	}
+	return Color{}, false
+}
+
+// mixUntil finds the smallest mix t of c toward the given extreme whose
+// contrast with other reaches target. Moving toward an extreme only ever
+// passes the target once, so a binary search finds it.
+func mixUntil(c, toward, other Color, target float64) (Color, float64, bool) {
+	mix := func(t float64) Color {
+		at := func(from, to uint8) uint8 {
+			return uint8(math.Round(float64(from) + (float64(to)-float64(from))*t))
+		}
+		return Color{R: at(c.R, toward.R), G: at(c.G, toward.G), B: at(c.B, toward.B), A: 1}
+	}
+	if Ratio(mix(1), other) < target {
+		return Color{}, 0, false
+	}
+	lo, hi := 0.0, 1.0
+	for i := 0; i < 24; i++ {
+		mid := (lo + hi) / 2
+		if Ratio(mix(mid), other) >= target {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return mix(hi), hi, true
+}
@@ -0,0 +1,165 @@
+package contrast
+
+import (
+	"math"
+	"testing"
+)
+
+func mustParse(t *testing.T, s string) Color {
+	t.Helper()
+	c, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", s, err)
+	}
+	return c
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Color
+	}{
+		{"#fff", Color{255, 255, 255, 1}},
+		{"#000000", Color{0, 0, 0, 1}},
+		{"#f008", Color{255, 0, 0, float64(0x88) / 255}},
+		{"#11223380", Color{0x11, 0x22, 0x33, float64(0x80) / 255}},
+		{"rgb(10, 20, 30)", Color{10, 20, 30, 1}},
+		{"rgba(10, 20, 30, 0.5)", Color{10, 20, 30, 0.5}},
+		{"rgba(10 20 30 / 0.25)", Color{10, 20, 30, 0.25}},
+		{"rgb(100%, 0%, 20%)", Color{255, 0, 51, 1}},
+		{"  RGB(1,2,3) ", Color{1, 2, 3, 1}},
+	}
+	for _, tt := range tests {
+		got := mustParse(t, tt.in)
+		if got.R != tt.want.R || got.G != tt.want.G || got.B != tt.want.B || math.Abs(got.A-tt.want.A) > 1e-9 {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{"", "red", "#12", "#12345", "#ggg", "rgb(1, 2)", "rgb(1, 2, x)", "rgb(1, 2, 3"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestRatio(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"#000", "#fff", 21},
+		{"#fff", "#000", 21},
+		{"#777", "#777", 1},
+		{"#767676", "#fff", 4.54},
+		{"#777777", "#fff", 4.48},
+		{"#595959", "#fff", 7.0},
+	}
+	for _, tt := range tests {
+		got := Ratio(mustParse(t, tt.a), mustParse(t, tt.b))
+		if math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("Ratio(%s, %s) = %.3f, want %.2f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRequired(t *testing.T) {
+	tests := []struct {
+		level Level
+		large bool
+		want  float64
+	}{
+		{AA, false, 4.5},
+		{AA, true, 3},
+		{AAA, false, 7},
+		{AAA, true, 4.5},
+	}
+	for _, tt := range tests {
+		if got := Required(tt.level, tt.large); got != tt.want {
+			t.Errorf("Required(%s, %v) = %v, want %v", tt.level, tt.large, got, tt.want)
+		}
+	}
+}
+
+func TestIsLargeText(t *testing.T) {
+	tests := []struct {
+		px   float64
+		bold bool
+		want bool
+	}{
+		{24, false, true},
+		{23.9, false, false},
+		{18.67, true, true},
+		{18.6, true, false},
+		{16, false, false},
+	}
+	for _, tt := range tests {
+		if got := IsLargeText(tt.px, tt.bold); got != tt.want {
+			t.Errorf("IsLargeText(%v, %v) = %v, want %v", tt.px, tt.bold, got, tt.want)
+		}
+	}
+}
+
+func TestAPCA(t *testing.T) {
+	tests := []struct {
+		text, bg string
+		want     float64
+	}{
+		{"#000", "#fff", 106.04},
+		{"#fff", "#000", -107.88},
+		{"#888", "#888", 0},
+	}
+	for _, tt := range tests {
+		got := APCA(mustParse(t, tt.text), mustParse(t, tt.bg))
+		if math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("APCA(%s, %s) = %.2f, want %.2f", tt.text, tt.bg, got, tt.want)
+		}
+	}
+}
+
+func TestOver(t *testing.T) {
+	got := mustParse(t, "rgba(0, 0, 0, 0.5)").Over(mustParse(t, "#fff"))
+	if got.Hex() != "#808080" || got.A != 1 {
+		t.Errorf("Over = %s (A %v), want #808080", got.Hex(), got.A)
+	}
+}
+
+func TestNearest(t *testing.T) {
+	tests := []struct {
+		c, other string
+		target   float64
+		ok       bool
+	}{
+		{"#777777", "#ffffff", 4.5, true},
+		{"#999999", "#000000", 7, true},
+		{"#bbbbbb", "#aaaaaa", 4.5, true},
+		{"#000000", "#ffffff", 4.5, true},
+		{"#777777", "#777777", 22, false},
+	}
+	for _, tt := range tests {
+		c, other := mustParse(t, tt.c), mustParse(t, tt.other)
+		got, ok := Nearest(c, other, tt.target)
+		if ok != tt.ok {
+			t.Errorf("Nearest(%s, %s, %v) ok = %v, want %v", tt.c, tt.other, tt.target, ok, tt.ok)
+			continue
+		}
+		if ok && Ratio(got, other) < tt.target {
+			t.Errorf("Nearest(%s, %s, %v) = %s with ratio %.2f", tt.c, tt.other, tt.target, got.Hex(), Ratio(got, other))
+		}
+	}
+}
+
+func TestNearestPicksSmallerMix(t *testing.T) {
+	// #767676 on #fff already passes darker by one step; lightening cannot.
+	got, ok := Nearest(mustParse(t, "#777777"), mustParse(t, "#fff"), 4.5)
+	if !ok || got.Hex() != "#767676" {
+		t.Errorf("Nearest(#777777, #fff) = %s, %v, want #767676", got.Hex(), ok)
+	}
+	// A light color on a mid grey is closer to white than to a dark enough grey.
+	got, ok = Nearest(mustParse(t, "#cccccc"), mustParse(t, "#555555"), 4.5)
+	if !ok || got.Luminance() < mustParse(t, "#cccccc").Luminance() {
+		t.Errorf("Nearest(#cccccc, #555555) = %s, %v, want a lighter color", got.Hex(), ok)
+	}
+}
@@ -0,0 +1,217 @@
+// Package crawler finds the pages to scan on a site, either by following links
+// from a start URL, breadth first and without leaving its host, or from a
+// sitemap or a list of URLs.
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// maxBodySize caps how much of each page is read when looking for links.
+const maxBodySize = 5 << 20
+
+// Options controls which pages a crawl visits.
+type Options struct {
+	StartURL string
+	MaxPages int
+	// MaxDepth is how many links away from the start page to follow; 0 only
+	// visits the start page.
+	MaxDepth int
+	// A page is kept when it matches at least one Include pattern (or there
+	// are none) and no Exclude pattern. The start page is always kept.
+	Include []*regexp.Regexp
+	Exclude []*regexp.Regexp
+	// Client defaults to NewClient.
+	Client *http.Client
+	// UserAgent is sent with every request when set.
+	UserAgent string
+}
+
+// Allowed reports whether u passes the Include and Exclude patterns.
+func (o Options) Allowed(u string) bool {
+	if o.excluded(u) {
+		return false
+	}
+	if len(o.Include) == 0 {
+		return true
+	}
+	for _, re := range o.Include {
+		if re.MatchString(u) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Options) excluded(u string) bool {
+	for _, re := range o.Exclude {
+		if re.MatchString(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchesPerPage bounds how many pages a crawl may load for each page it
+// keeps, since pages that fail Include are still loaded for their links.
+const fetchesPerPage = 20
+
+// Page is an HTML page found during a crawl.
+type Page struct {
+	URL   string
+	Depth int
+}
+
+// Crawl visits pages breadth first and calls visit for each HTML page found
+// that passes Include and Exclude, up to MaxPages. Links are followed from
+// every page within MaxDepth, including ones Include leaves out, so pages only
+// reachable through them are still found; excluded URLs are not loaded at
+// all. Pages that fail to load are skipped; only a failure to load the start
+// page, an error from visit or ctx ending stops the crawl early.
+func Crawl(ctx context.Context, opts Options, visit func(Page) error) error {
+	start, err := url.Parse(opts.StartURL)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		return fmt.Errorf("invalid url %q", opts.StartURL)
+	}
+	start.Fragment = ""
+	client := opts.Client
+	if client == nil {
+		client = NewClient(15 * time.Second)
+	}
+
+	queue := []Page{{URL: start.String()}}
+	queued := map[string]bool{start.String(): true}
+	// Several links can redirect to the same page, so visited is keyed by
+	// where the request ended up.
+	visited := map[string]bool{}
+	kept, fetches := 0, 0
+	for len(queue) > 0 && kept < opts.MaxPages && fetches < opts.MaxPages*fetchesPerPage {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page := queue[0]
+		queue = queue[1:]
+		fetches++
+		final, links, err := fetch(ctx, client, opts.UserAgent, page.URL)
+		if err != nil {
+			if page.Depth == 0 {
+				return err
+			}
+			continue
+		}
+		if final == nil || final.Hostname() != start.Hostname() || visited[final.String()] {
+			continue
+		}
+		page.URL = final.String()
+		visited[page.URL] = true
+		// The start page is always kept.
+		if page.Depth == 0 || opts.Allowed(page.URL) {
+			kept++
+			if err := visit(page); err != nil {
+				return err
+			}
+		}
+		if page.Depth >= opts.MaxDepth {
+			continue
+		}
+		for _, link := range links {
+			next, err := final.Parse(link)
+			if err != nil || (next.Scheme != "http" && next.Scheme != "https") || next.Hostname() != start.Hostname() {
+				continue
+			}
+			next.Fragment = ""
+			key := next.String()
+			if queued[key] || opts.excluded(key) {
+				continue
+			}
+			queued[key] = true
+			queue = append(queue, Page{URL: key, Depth: page.Depth + 1})
+		}
+	}
+	return nil
+}
+
+// fetch loads rawURL and returns the URL it ended up at after redirects and
+// the links on it. final is nil when the response is not an HTML page.
+func fetch(ctx context.Context, client *http.Client, userAgent, rawURL string) (final *url.URL, links []string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, nil, nil
+	}
+	final = resp.Request.URL
+	final.Fragment = ""
+	links, base := extractLinks(io.LimitReader(resp.Body, maxBodySize))
+	if base != "" {
+		if b, err := final.Parse(base); err == nil {
+			// Links resolve against <base href>, but the page keeps its own URL.
+			resolved := make([]string, 0, len(links))
+			for _, link := range links {
+				if u, err := b.Parse(link); err == nil {
+					resolved = append(resolved, u.String())
+				}
+			}
+			links = resolved
+		}
+	}
+	return final, links, nil
+}
+
+// extractLinks returns the href of every followable <a> and <area>, and the
+// document's <base href> if it has one.
+func extractLinks(r io.Reader) (links []string, base string) {
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return links, base
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "a", "area":
+				href, rel := attr(tok, "href"), attr(tok, "rel")
+				if href != "" && !strings.Contains(strings.ToLower(rel), "nofollow") {
+					links = append(links, href)
+				}
+			case "base":
+				if base == "" {
+					base = attr(tok, "href")
+				}
+			}
+		}
+	}
+}
+
+func attr(tok html.Token, name string) string {
+	for _, a := range tok.Attr {
+		if a.Key == name {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
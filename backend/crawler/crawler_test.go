@@ -0,0 +1,133 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"fd00:ec2::254", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublic(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("IsPublic(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestNewClientRefusesInternalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("internal server was reached: %s", r.URL)
+	}))
+	defer srv.Close()
+	_, err := NewClient(5*time.Second).Get(srv.URL)
+	if !errors.Is(err, ErrNotPublic) {
+		t.Fatalf("Get(%s) error = %v, want ErrNotPublic", srv.URL, err)
+	}
+
+	err = Crawl(context.Background(), Options{StartURL: srv.URL, MaxPages: 5}, func(Page) error { return nil })
+	if !errors.Is(err, ErrNotPublic) {
+		t.Errorf("Crawl(%s) error = %v, want ErrNotPublic", srv.URL, err)
+	}
+}
+
+func TestSameHostRedirect(t *testing.T) {
+	req := func(u string) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, u, nil)
+		return r
+	}
+	start := req("https://example.com/")
+	if err := sameHostRedirect(req("https://EXAMPLE.com:8443/login"), []*http.Request{start}); err != nil {
+		t.Errorf("redirect on the same host refused: %v", err)
+	}
+	if err := sameHostRedirect(req("http://169.254.169.254/latest/meta-data"), []*http.Request{start}); err == nil {
+		t.Errorf("redirect to another host followed")
+	}
+	via := make([]*http.Request, maxRedirects)
+	for i := range via {
+		via[i] = start
+	}
+	if err := sameHostRedirect(req("https://example.com/loop"), via); err == nil {
+		t.Errorf("redirect loop followed past %d redirects", maxRedirects)
+	}
+}
+
+// site serves linked HTML pages; a path maps to its links.
+func site(t *testing.T, pages map[string][]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		links, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html><body>")
+		for _, l := range links {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, l)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCrawl(t *testing.T) {
+	srv := site(t, map[string][]string{
+		"/":               {"/docs/", "/blog/", "/private/x", "https://other.example/", "mailto:a@b.c", "/#top"},
+		"/docs/":          {"/docs/a", "/blog/"},
+		"/docs/a":         {"/docs/b"},
+		"/docs/b":         nil,
+		"/blog/":          {"/docs/from-blog"},
+		"/docs/from-blog": nil,
+		"/private/x":      nil,
+	})
+	var got []string
+	err := Crawl(context.Background(), Options{
+		StartURL: srv.URL + "/",
+		MaxPages: 10,
+		MaxDepth: 2,
+		Include:  []*regexp.Regexp{regexp.MustCompile(`/docs/`)},
+		Exclude:  []*regexp.Regexp{regexp.MustCompile(`/private/`)},
+		Client:   srv.Client(),
+	}, func(p Page) error {
+		got = append(got, fmt.Sprintf("%s@%d", p.URL[len(srv.URL):], p.Depth))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	// /blog/ is not kept but its links are followed; /docs/b is too deep.
+	want := []string{"/@0", "/docs/@1", "/docs/a@2", "/docs/from-blog@2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("visited %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,159 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// maxSitemapSize is the uncompressed size limit the sitemap protocol sets.
+	maxSitemapSize = 50 << 20
+	// maxSitemapDepth bounds how far nested sitemap indexes are followed.
+	maxSitemapDepth = 3
+)
+
+// sitemapDoc decodes both a <urlset> and a <sitemapindex>.
+type sitemapDoc struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// FetchSitemap returns the page URLs listed in the sitemap at sitemapURL,
+// following sitemap indexes and gunzipping .xml.gz files, up to limit URLs.
+// Child sitemaps that fail to load are skipped. Only URLs and child sitemaps
+// on the sitemap's own host are used. client defaults to NewClient.
+func FetchSitemap(ctx context.Context, client *http.Client, userAgent, sitemapURL string, limit int) ([]string, error) {
+	root, err := url.Parse(sitemapURL)
+	if err != nil || (root.Scheme != "http" && root.Scheme != "https") || root.Hostname() == "" {
+		return nil, fmt.Errorf("invalid url %q", sitemapURL)
+	}
+	onHost := func(loc string) bool {
+		u, err := url.Parse(loc)
+		return err == nil && (u.Scheme == "http" || u.Scheme == "https") && strings.EqualFold(u.Hostname(), root.Hostname())
+	}
+	if client == nil {
+		client = NewClient(30 * time.Second)
+	}
+	var urls []string
+	seen := map[string]bool{}
+	var walk func(string, int) error
+	walk = func(loc string, depth int) error {
+		if seen[loc] {
+			return nil
+		}
+		seen[loc] = true
+		doc, err := fetchSitemap(ctx, client, userAgent, loc)
+		if err != nil {
+			return err
+		}
+		for _, u := range doc.URLs {
+			if len(urls) >= limit {
+				return nil
+			}
+			if loc := strings.TrimSpace(u.Loc); onHost(loc) {
+				urls = append(urls, loc)
+			}
+		}
+		if depth >= maxSitemapDepth {
+			return nil
+		}
+		for _, child := range doc.Sitemaps {
+			if len(urls) >= limit {
+				return nil
+			}
+			loc := strings.TrimSpace(child.Loc)
+			if !onHost(loc) {
+				continue
+			}
+			if err := walk(loc, depth+1); err != nil && ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+	if err := walk(sitemapURL, 0); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+func fetchSitemap(ctx context.Context, client *http.Client, userAgent, sitemapURL string) (*sitemapDoc, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %q", sitemapURL)
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("GET %s: %s", sitemapURL, resp.Status)
+	}
+	body := bufio.NewReader(resp.Body)
+	var r io.Reader = body
+	// A .xml.gz file is served as a gzip body rather than with a gzip
+	// Content-Encoding, so check the magic bytes instead of the headers.
+	if magic, err := body.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("sitemap %s: %w", sitemapURL, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	var doc sitemapDoc
+	if err := xml.NewDecoder(io.LimitReader(r, maxSitemapSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("sitemap %s is not valid XML: %w", sitemapURL, err)
+	}
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return nil, fmt.Errorf("sitemap %s has unexpected root <%s>", sitemapURL, doc.XMLName.Local)
+	}
+	return &doc, nil
+}
+
+// ParseURLList reads URLs from a plain text list, one per line, or a CSV
+// file. Every field that starts with http:// or https:// is taken, so header
+// rows and extra columns are ignored, as are lines starting with #.
+func ParseURLList(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	var urls []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return urls, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range record {
+			field = strings.TrimSpace(field)
+			lower := strings.ToLower(field)
+			if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+				urls = append(urls, field)
+			}
+		}
+	}
+}
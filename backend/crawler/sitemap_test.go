@@ -0,0 +1,169 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func urlset(locs ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, l := range locs {
+		fmt.Fprintf(&b, "<url><loc>%s</loc></url>", l)
+	}
+	b.WriteString("</urlset>")
+	return b.String()
+}
+
+func sitemapIndex(locs ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, l := range locs {
+		fmt.Fprintf(&b, "<sitemap><loc>%s</loc></sitemap>", l)
+	}
+	b.WriteString("</sitemapindex>")
+	return b.String()
+}
+
+func gzipped(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// sitemapServer serves the documents built by files, which gets the server's
+// base URL. Requests for anything else are recorded and answered 404.
+func sitemapServer(t *testing.T, files func(base string) map[string]string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var missed []string
+	var docs map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := docs[r.URL.Path]
+		if !ok {
+			missed = append(missed, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, ".gz") {
+			w.Header().Set("Content-Type", "application/gzip")
+		} else {
+			w.Header().Set("Content-Type", "application/xml")
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	docs = files(srv.URL)
+	return srv, &missed
+}
+
+func TestFetchSitemap(t *testing.T) {
+	srv, missed := sitemapServer(t, func(base string) map[string]string {
+		return map[string]string{
+			"/sitemap.xml":  sitemapIndex(base+"/pages.xml", base+"/posts.xml.gz", base+"/missing.xml", "https://evil.example/sitemap.xml", base+"/pages.xml"),
+			"/pages.xml":    urlset(base+"/", base+"/about", "http://169.254.169.254/latest/meta-data/", "  "+base+"/contact  "),
+			"/posts.xml.gz": gzipped(t, urlset(base+"/posts/1", "ftp://"+strings.TrimPrefix(base, "http://")+"/file")),
+		}
+	})
+	got, err := FetchSitemap(context.Background(), srv.Client(), "test-agent", srv.URL+"/sitemap.xml", 100)
+	if err != nil {
+		t.Fatalf("FetchSitemap: %v", err)
+	}
+	want := []string{srv.URL + "/", srv.URL + "/about", srv.URL + "/contact", srv.URL + "/posts/1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FetchSitemap = %v, want %v", got, want)
+	}
+	// The broken child is skipped; the off-host one is never requested.
+	if !reflect.DeepEqual(*missed, []string{"/missing.xml"}) {
+		t.Errorf("unexpected requests %v", *missed)
+	}
+}
+
+func TestFetchSitemapLimit(t *testing.T) {
+	srv, _ := sitemapServer(t, func(base string) map[string]string {
+		return map[string]string{
+			"/sitemap.xml": sitemapIndex(base+"/a.xml", base+"/b.xml"),
+			"/a.xml":       urlset(base+"/1", base+"/2"),
+			"/b.xml":       urlset(base+"/3", base+"/4"),
+		}
+	})
+	got, err := FetchSitemap(context.Background(), srv.Client(), "", srv.URL+"/sitemap.xml", 3)
+	if err != nil {
+		t.Fatalf("FetchSitemap: %v", err)
+	}
+	if want := []string{srv.URL + "/1", srv.URL + "/2", srv.URL + "/3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FetchSitemap = %v, want %v", got, want)
+	}
+}
+
+func TestFetchSitemapDepth(t *testing.T) {
+	srv, missed := sitemapServer(t, func(base string) map[string]string {
+		docs := map[string]string{}
+		for i := 0; i <= maxSitemapDepth+1; i++ {
+			docs[fmt.Sprintf("/level%d.xml", i)] = sitemapIndex(fmt.Sprintf("%s/level%d.xml", base, i+1))
+		}
+		return docs
+	})
+	if _, err := FetchSitemap(context.Background(), srv.Client(), "", srv.URL+"/level0.xml", 10); err != nil {
+		t.Fatalf("FetchSitemap: %v", err)
+	}
+	if len(*missed) != 0 {
+		t.Errorf("followed indexes past depth %d: %v", maxSitemapDepth, *missed)
+	}
+}
+
+func TestFetchSitemapErrors(t *testing.T) {
+	srv, _ := sitemapServer(t, func(base string) map[string]string {
+		return map[string]string{
+			"/html.xml":   "<html><body>not a sitemap</body></html>",
+			"/broken.xml": "<urlset><url>",
+			"/bad.xml.gz": "\x1f\x8bnot really gzip",
+		}
+	})
+	for _, path := range []string{"/html.xml", "/broken.xml", "/bad.xml.gz", "/missing.xml"} {
+		if _, err := FetchSitemap(context.Background(), srv.Client(), "", srv.URL+path, 10); err == nil {
+			t.Errorf("FetchSitemap(%s) succeeded, want an error", path)
+		}
+	}
+	if _, err := FetchSitemap(context.Background(), srv.Client(), "", "file:///etc/passwd", 10); err == nil {
+		t.Errorf("FetchSitemap accepted a file URL")
+	}
+}
+
+func TestParseURLList(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"plain lines", "https://a.example/\n\nhttp://b.example/x\n", []string{"https://a.example/", "http://b.example/x"}},
+		{"comments and junk", "# pages\nhttps://a.example/\nnot a url\nftp://c.example/\n", []string{"https://a.example/"}},
+		{"csv with header", "url,title\nhttps://a.example/,Home\n\"https://b.example/?q=1,2\",Search\n", []string{"https://a.example/", "https://b.example/?q=1,2"}},
+		{"several per row", "HTTPS://A.example/, https://b.example/ \n", []string{"HTTPS://A.example/", "https://b.example/"}},
+		{"ragged rows", "https://a.example/\nhttps://b.example/,x,y\n", []string{"https://a.example/", "https://b.example/"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		got, err := ParseURLList(strings.NewReader(tt.in))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseURLList = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
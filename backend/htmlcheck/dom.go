@@ -0,0 +1,283 @@
+package htmlcheck
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxHTMLLength is how much markup is kept per node before it is shortened to
+// the start tag, as axe does.
+const maxHTMLLength = 300
+
+// document is a parsed page with the lookups rules share.
+type document struct {
+	root *html.Node
+	// elements lists every element in document order, outside <template>.
+	elements []*html.Node
+	ids      map[string][]*html.Node
+}
+
+func newDocument(root *html.Node) *document {
+	d := &document{root: root, ids: map[string][]*html.Node{}}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.DataAtom == atom.Template {
+				return
+			}
+			d.elements = append(d.elements, n)
+			if id, ok := attr(n, "id"); ok && id != "" {
+				d.ids[id] = append(d.ids[id], n)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return d
+}
+
+// byTag returns the elements with one of the given tag names.
+func (d *document) byTag(tags ...atom.Atom) []*html.Node {
+	var out []*html.Node
+	for _, el := range d.elements {
+		for _, t := range tags {
+			if el.DataAtom == t {
+				out = append(out, el)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func (d *document) htmlElement() *html.Node {
+	if els := d.byTag(atom.Html); len(els) > 0 {
+		return els[0]
+	}
+	return nil
+}
+
+var cssIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// selector builds a CSS selector for el that matches only el: its id when
+// that is unique, otherwise a child path from the nearest such ancestor or
+// from <html>.
+func (d *document) selector(el *html.Node) string {
+	var parts []string
+	for n := el; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if id, ok := attr(n, "id"); ok && cssIdent.MatchString(id) && len(d.ids[id]) == 1 {
+			parts = append(parts, "#"+id)
+			break
+		}
+		part := n.Data
+		if index, shared := childIndex(n); shared {
+			part = fmt.Sprintf("%s:nth-child(%d)", n.Data, index)
+		}
+		parts = append(parts, part)
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, " > ")
+}
+
+// childIndex returns el's 1-based position among its parent's element
+// children and whether a sibling has the same tag.
+func childIndex(el *html.Node) (int, bool) {
+	index, shared := 1, false
+	if el.Parent == nil {
+		return index, shared
+	}
+	before := true
+	for c := el.Parent.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c == el:
+			before = false
+		case c.Type != html.ElementNode:
+		default:
+			if before {
+				index++
+			}
+			if c.Data == el.Data {
+				shared = true
+			}
+		}
+	}
+	return index, shared
+}
+
+// outerHTML renders el, or only its start tag when the whole element is long.
+func outerHTML(el *html.Node) string {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, el); err == nil && buf.Len() <= maxHTMLLength {
+		return buf.String()
+	}
+	start := &html.Node{Type: html.ElementNode, Data: el.Data, DataAtom: el.DataAtom, Attr: el.Attr}
+	buf.Reset()
+	_ = html.Render(&buf, start)
+	tag := strings.TrimSuffix(buf.String(), "</"+el.Data+">")
+	if len(tag) > maxHTMLLength {
+		tag = tag[:maxHTMLLength]
+	}
+	return tag + "..."
+}
+
+func attr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attrValue(n *html.Node, name string) string {
+	v, _ := attr(n, name)
+	return strings.TrimSpace(v)
+}
+
+var hiddenStyle = regexp.MustCompile(`(?i)(display\s*:\s*none|visibility\s*:\s*hidden)`)
+
+// isHidden reports whether el or an ancestor is hidden from everyone through
+// the hidden attribute or an inline style.
+func isHidden(el *html.Node) bool {
+	for n := el; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		if _, ok := attr(n, "hidden"); ok {
+			return true
+		}
+		if hiddenStyle.MatchString(attrValue(n, "style")) {
+			return true
+		}
+		if n.DataAtom == atom.Input && strings.EqualFold(attrValue(n, "type"), "hidden") {
+			return true
+		}
+	}
+	return false
+}
+
+// isAriaHidden reports whether el or an ancestor is hidden from assistive
+// technology.
+func isAriaHidden(el *html.Node) bool {
+	for n := el; n != nil; n = n.Parent {
+		if n.Type == html.ElementNode && attrValue(n, "aria-hidden") == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// ariaName returns the name given by aria-labelledby or aria-label.
+func (d *document) ariaName(el *html.Node) string {
+	if ids := strings.Fields(attrValue(el, "aria-labelledby")); len(ids) > 0 {
+		var parts []string
+		for _, id := range ids {
+			if refs := d.ids[id]; len(refs) > 0 {
+				if text := visibleText(refs[0]); text != "" {
+					parts = append(parts, text)
+				}
+			}
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, " ")
+		}
+	}
+	return attrValue(el, "aria-label")
+}
+
+// visibleText returns the text of el's subtree as a screen reader would read
+// it, counting the alt text of images and skipping hidden parts.
+func visibleText(el *html.Node) string {
+	var buf strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			buf.WriteString(n.Data)
+			buf.WriteByte(' ')
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Script, atom.Style, atom.Template, atom.Noscript:
+				return
+			}
+			if _, ok := attr(n, "hidden"); ok || attrValue(n, "aria-hidden") == "true" {
+				return
+			}
+			if hiddenStyle.MatchString(attrValue(n, "style")) {
+				return
+			}
+			if label := attrValue(n, "aria-label"); label != "" {
+				buf.WriteString(label)
+				buf.WriteByte(' ')
+				return
+			}
+			if n.DataAtom == atom.Img || (n.DataAtom == atom.Input && strings.EqualFold(attrValue(n, "type"), "image")) {
+				buf.WriteString(attrValue(n, "alt"))
+				buf.WriteByte(' ')
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(el)
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// hasRole reports whether el's role attribute starts with one of roles.
+func hasRole(el *html.Node, roles ...string) bool {
+	fields := strings.Fields(strings.ToLower(attrValue(el, "role")))
+	if len(fields) == 0 {
+		return false
+	}
+	for _, r := range roles {
+		if fields[0] == r {
+			return true
+		}
+	}
+	return false
+}
+
+// closest returns the nearest ancestor of el with tag t.
+func closest(el *html.Node, t atom.Atom) *html.Node {
+	for n := el.Parent; n != nil; n = n.Parent {
+		if n.Type == html.ElementNode && n.DataAtom == t {
+			return n
+		}
+	}
+	return nil
+}
+
+// within returns the elements of tag t in el's subtree, not counting nested
+// tables when el is a table.
+func within(el *html.Node, t atom.Atom) []*html.Node {
+	var out []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c.DataAtom == t {
+				out = append(out, c)
+			}
+			if c.DataAtom == atom.Table && el.DataAtom == atom.Table {
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(el)
+	return out
+}
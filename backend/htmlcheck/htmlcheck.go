@@ -0,0 +1,181 @@
+// Package htmlcheck tests an HTML document for common accessibility problems
+// without a browser. It only sees the markup as written, so it cannot judge
+// styles, scripts or layout, but it is fast enough for pre-commit checks. Its
+// rules use axe-core's ids and tags and its results axe-core's schema, so
+// they can be stored and scored like any other scan.
+package htmlcheck
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"backend/models"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	engineName    = "htmlcheck"
+	engineVersion = "1.0.0"
+	helpURLBase   = "https://dequeuniversity.com/rules/axe/4.8/"
+)
+
+// Options selects which rules run, the way axe's runOnly and rules options do.
+type Options struct {
+	// Tags keeps only the rules tagged with at least one of them.
+	Tags []string
+	// Rules turns single rules on or off regardless of Tags.
+	Rules map[string]bool
+}
+
+// RuleIDs lists the rules the checker knows.
+func RuleIDs() []string {
+	ids := make([]string, len(rules))
+	for i, r := range rules {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+// Check parses the document read from r and runs the selected rules against
+// it. pageURL is only copied into the results.
+func Check(r io.Reader, pageURL string, opts Options) (*models.AxeResults, error) {
+	for id := range opts.Rules {
+		if findRule(id) == nil {
+			return nil, fmt.Errorf("unknown rule `%s` in options.rules", id)
+		}
+	}
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+	doc := newDocument(root)
+	results := &models.AxeResults{
+		TestEngine: models.AxeTestEngine{Name: engineName, Version: engineVersion},
+		TestRunner: models.AxeTestRunner{Name: engineName},
+		URL:        pageURL,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+	}
+	results.Violations = []models.AxeRule{}
+	results.Passes = []models.AxeRule{}
+	results.Incomplete = []models.AxeRule{}
+	results.Inapplicable = []models.AxeRule{}
+	for i := range rules {
+		r := &rules[i]
+		if !opts.selects(r) {
+			continue
+		}
+		var passed, failed []models.AxeNode
+		for _, res := range r.check(doc) {
+			node := r.node(doc, res)
+			if res.pass {
+				passed = append(passed, node)
+			} else {
+				failed = append(failed, node)
+			}
+		}
+		switch {
+		case len(passed) == 0 && len(failed) == 0:
+			results.Inapplicable = append(results.Inapplicable, r.result(r.Impact, []models.AxeNode{}))
+		default:
+			if len(failed) > 0 {
+				results.Violations = append(results.Violations, r.result(r.Impact, failed))
+			}
+			if len(passed) > 0 {
+				results.Passes = append(results.Passes, r.result("", passed))
+			}
+		}
+	}
+	return results, nil
+}
+
+func (o Options) selects(r *rule) bool {
+	if on, ok := o.Rules[r.ID]; ok {
+		return on
+	}
+	if len(o.Tags) == 0 {
+		return true
+	}
+	for _, want := range o.Tags {
+		for _, tag := range r.Tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func findRule(id string) *rule {
+	for i := range rules {
+		if rules[i].ID == id {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// rule is one check, described the way axe-core describes its rule of the
+// same id.
+type rule struct {
+	ID          string
+	Impact      string
+	Tags        []string
+	Description string
+	Help        string
+	// CheckID names the check recorded on each node.
+	CheckID string
+	check   func(d *document) []result
+}
+
+// result is the outcome of a rule for one element.
+type result struct {
+	el      *html.Node
+	pass    bool
+	message string
+	related []*html.Node
+}
+
+func (r *rule) result(impact string, nodes []models.AxeNode) models.AxeRule {
+	return models.AxeRule{
+		ID:          r.ID,
+		Impact:      impact,
+		Tags:        r.Tags,
+		Description: r.Description,
+		Help:        r.Help,
+		HelpURL:     helpURLBase + r.ID,
+		Nodes:       nodes,
+	}
+}
+
+func (r *rule) node(d *document, res result) models.AxeNode {
+	impact := ""
+	if !res.pass {
+		impact = r.Impact
+	}
+	check := models.AxeCheck{
+		ID:           r.CheckID,
+		Impact:       r.Impact,
+		Message:      res.message,
+		RelatedNodes: []models.AxeRelatedNode{},
+	}
+	for _, el := range res.related {
+		check.RelatedNodes = append(check.RelatedNodes, models.AxeRelatedNode{
+			HTML:   outerHTML(el),
+			Target: models.AxeTarget{d.selector(el)},
+		})
+	}
+	node := models.AxeNode{
+		HTML:   outerHTML(res.el),
+		Impact: impact,
+		Target: models.AxeTarget{d.selector(res.el)},
+		Any:    []models.AxeCheck{check},
+		All:    []models.AxeCheck{},
+		None:   []models.AxeCheck{},
+	}
+	if !res.pass {
+		node.FailureSummary = "Fix any of the following:\n  " + res.message
+	}
+	return node
+}
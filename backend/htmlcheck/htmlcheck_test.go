@@ -0,0 +1,204 @@
+package htmlcheck
+
+import (
+	"strings"
+	"testing"
+
+	"backend/models"
+
+	"golang.org/x/net/html"
+)
+
+// page wraps body in a document that passes the page-level rules.
+func page(body string) string {
+	return `<!DOCTYPE html><html lang="en"><head><title>Test</title></head><body>` + body + `</body></html>`
+}
+
+// only runs a single rule against markup.
+func only(t *testing.T, ruleID, markup string) *models.AxeResults {
+	t.Helper()
+	results, err := Check(strings.NewReader(markup), "https://example.com/", Options{
+		Tags:  []string{"no-such-tag"},
+		Rules: map[string]bool{ruleID: true},
+	})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	return results
+}
+
+func nodeCount(rules []models.AxeRule, id string) int {
+	for _, r := range rules {
+		if r.ID == id {
+			return len(r.Nodes)
+		}
+	}
+	return 0
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule   string
+		name   string
+		markup string
+		passes int
+		fails  int
+	}{
+		{"document-title", "has title", page(""), 1, 0},
+		{"document-title", "missing title", `<html lang="en"><head></head><body></body></html>`, 0, 1},
+		{"document-title", "blank title", `<html lang="en"><head><title>  </title></head></html>`, 0, 1},
+
+		{"html-has-lang", "has lang", page(""), 1, 0},
+		{"html-has-lang", "missing lang", `<html><head><title>T</title></head></html>`, 0, 1},
+
+		{"image-alt", "alt text", page(`<img src="a.png" alt="Logo">`), 1, 0},
+		{"image-alt", "empty alt", page(`<img src="a.png" alt="">`), 1, 0},
+		{"image-alt", "presentational", page(`<img src="a.png" role="presentation">`), 1, 0},
+		{"image-alt", "no alt", page(`<img src="a.png">`), 0, 1},
+		{"image-alt", "hidden", page(`<img src="a.png" aria-hidden="true">`), 0, 0},
+
+		{"input-image-alt", "alt text", page(`<input type="image" src="go.png" alt="Search">`), 1, 0},
+		{"input-image-alt", "empty alt", page(`<input type="image" src="go.png" alt="">`), 0, 1},
+
+		{"link-name", "text", page(`<a href="/">Home</a>`), 1, 0},
+		{"link-name", "image alt", page(`<a href="/"><img src="h.png" alt="Home"></a>`), 1, 0},
+		{"link-name", "aria-labelledby", page(`<span id="l">Home</span><a href="/" aria-labelledby="l"></a>`), 1, 0},
+		{"link-name", "empty", page(`<a href="/"><img src="h.png" alt=""></a>`), 0, 1},
+		{"link-name", "no href", page(`<a name="top"></a>`), 0, 0},
+
+		{"button-name", "text", page(`<button>Save</button>`), 1, 0},
+		{"button-name", "default submit label", page(`<input type="submit">`), 1, 0},
+		{"button-name", "empty button", page(`<button></button>`), 0, 1},
+		{"button-name", "empty value", page(`<input type="button" value="">`), 0, 1},
+		{"button-name", "role button", page(`<div role="button"></div>`), 0, 1},
+
+		{"label", "explicit", page(`<label for="q">Search</label><input id="q">`), 1, 0},
+		{"label", "implicit", page(`<label>Search <input></label>`), 1, 0},
+		{"label", "aria-label", page(`<textarea aria-label="Message"></textarea>`), 1, 0},
+		{"label", "unlabelled", page(`<input type="text"><select><option>A</option></select>`), 0, 2},
+		{"label", "hidden input", page(`<input type="hidden" name="t">`), 0, 0},
+
+		{"duplicate-id", "unique", page(`<div id="a"></div><div id="b"></div>`), 2, 0},
+		{"duplicate-id", "duplicated", page(`<div id="a"></div><div id="a"></div>`), 0, 1},
+
+		{"heading-order", "in order", page(`<h1>A</h1><h2>B</h2><h3>C</h3><h2>D</h2>`), 4, 0},
+		{"heading-order", "skips a level", page(`<h1>A</h1><h3>B</h3>`), 1, 1},
+
+		{"th-has-data-cells", "with data", page(`<table><tr><th>Name</th></tr><tr><td>Ann</td></tr></table>`), 1, 0},
+		{"th-has-data-cells", "headers only", page(`<table><tr><th>Name</th><th>Age</th></tr></table>`), 0, 2},
+		{"th-has-data-cells", "layout table", page(`<table role="presentation"><tr><th>Name</th></tr></table>`), 0, 0},
+
+		{"td-headers-attr", "same table", page(`<table><tr><th id="n">Name</th></tr><tr><td headers="n">Ann</td></tr></table>`), 1, 0},
+		{"td-headers-attr", "other table", page(`<table><tr><th id="n">Name</th></tr></table><table><tr><td headers="n">Ann</td></tr></table>`), 0, 1},
+		{"td-headers-attr", "self reference", page(`<table><tr><td id="c" headers="c">Ann</td></tr></table>`), 0, 1},
+
+		{"scope-attr-valid", "valid", page(`<table><tr><th scope="col">Name</th></tr></table>`), 1, 0},
+		{"scope-attr-valid", "bad value", page(`<table><tr><th scope="column">Name</th></tr></table>`), 0, 1},
+		{"scope-attr-valid", "on td", page(`<table><tr><td scope="row">Ann</td></tr></table>`), 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule+"/"+tt.name, func(t *testing.T) {
+			results := only(t, tt.rule, tt.markup)
+			if got := nodeCount(results.Passes, tt.rule); got != tt.passes {
+				t.Errorf("passes = %d, want %d", got, tt.passes)
+			}
+			if got := nodeCount(results.Violations, tt.rule); got != tt.fails {
+				t.Errorf("violations = %d, want %d", got, tt.fails)
+			}
+			if tt.passes == 0 && tt.fails == 0 && len(results.Inapplicable) != 1 {
+				t.Errorf("inapplicable = %d rules, want 1", len(results.Inapplicable))
+			}
+		})
+	}
+}
+
+func TestEveryRuleHasFixtures(t *testing.T) {
+	// Keep TestRules in step with the rule list.
+	covered := map[string]bool{
+		"document-title": true, "html-has-lang": true, "image-alt": true, "input-image-alt": true,
+		"link-name": true, "button-name": true, "label": true, "duplicate-id": true,
+		"heading-order": true, "th-has-data-cells": true, "td-headers-attr": true, "scope-attr-valid": true,
+	}
+	for _, id := range RuleIDs() {
+		if !covered[id] {
+			t.Errorf("rule %s has no fixtures", id)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	markup := page(`<img src="a.png">`)
+	results, err := Check(strings.NewReader(markup), "", Options{Tags: []string{"best-practice"}})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if nodeCount(results.Violations, "image-alt") != 0 {
+		t.Errorf("image-alt ran although it is not tagged best-practice")
+	}
+	results, err = Check(strings.NewReader(markup), "", Options{Rules: map[string]bool{"image-alt": false}})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if nodeCount(results.Violations, "image-alt") != 0 {
+		t.Errorf("image-alt ran although it was turned off")
+	}
+	if _, err := Check(strings.NewReader(markup), "", Options{Rules: map[string]bool{"no-such-rule": true}}); err == nil {
+		t.Errorf("Check accepted an unknown rule")
+	}
+}
+
+func TestSelector(t *testing.T) {
+	tests := []struct {
+		name   string
+		markup string
+		find   func(*html.Node) bool
+		want   string
+	}{
+		{
+			"unique id",
+			page(`<div><p id="intro">Hi</p></div>`),
+			func(n *html.Node) bool { return n.Data == "p" },
+			"#intro",
+		},
+		{
+			"child path from unique ancestor id",
+			page(`<ul id="menu"><li>A</li><li><a href="/b">B</a></li></ul>`),
+			func(n *html.Node) bool { return n.Data == "a" },
+			"#menu > li:nth-child(2) > a",
+		},
+		{
+			"duplicate id falls back to path",
+			page(`<p id="x">A</p><p id="x">B</p>`),
+			func(n *html.Node) bool { return n.Data == "p" && visibleText(n) == "B" },
+			"html > body > p:nth-child(2)",
+		},
+		{
+			"no ids",
+			page(`<main><span>A</span></main>`),
+			func(n *html.Node) bool { return n.Data == "span" },
+			"html > body > main > span",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := html.Parse(strings.NewReader(tt.markup))
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := newDocument(root)
+			var el *html.Node
+			for _, n := range d.elements {
+				if tt.find(n) {
+					el = n
+					break
+				}
+			}
+			if el == nil {
+				t.Fatal("element not found")
+			}
+			if got := d.selector(el); got != tt.want {
+				t.Errorf("selector = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,448 @@
+package htmlcheck
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var rules = []rule{
+	{
+		ID:          "document-title",
+		Impact:      "serious",
+		Tags:        []string{"cat.text-alternatives", "wcag2a", "wcag242"},
+		Description: "Ensures each HTML document contains a non-empty <title> element",
+		Help:        "Documents must have <title> element to aid in navigation",
+		CheckID:     "doc-has-title",
+		check:       checkDocumentTitle,
+	},
+	{
+		ID:          "html-has-lang",
+		Impact:      "serious",
+		Tags:        []string{"cat.language", "wcag2a", "wcag311"},
+		Description: "Ensures every HTML document has a lang attribute",
+		Help:        "<html> element must have a lang attribute",
+		CheckID:     "has-lang",
+		check:       checkHTMLLang,
+	},
+	{
+		ID:          "image-alt",
+		Impact:      "critical",
+		Tags:        []string{"cat.text-alternatives", "wcag2a", "wcag111", "section508", "section508.22.a"},
+		Description: "Ensures <img> elements have alternate text or a role of none or presentation",
+		Help:        "Images must have alternate text",
+		CheckID:     "has-alt",
+		check:       checkImageAlt,
+	},
+	{
+		ID:          "input-image-alt",
+		Impact:      "critical",
+		Tags:        []string{"cat.text-alternatives", "wcag2a", "wcag111", "wcag412", "section508", "section508.22.a"},
+		Description: "Ensures <input type=\"image\"> elements have alternate text",
+		Help:        "Image buttons must have alternate text",
+		CheckID:     "non-empty-alt",
+		check:       checkInputImageAlt,
+	},
+	{
+		ID:          "link-name",
+		Impact:      "serious",
+		Tags:        []string{"cat.name-role-value", "wcag2a", "wcag244", "wcag412", "section508", "section508.22.a"},
+		Description: "Ensures links have discernible text",
+		Help:        "Links must have discernible text",
+		CheckID:     "has-visible-text",
+		check:       checkLinkName,
+	},
+	{
+		ID:          "button-name",
+		Impact:      "critical",
+		Tags:        []string{"cat.name-role-value", "wcag2a", "wcag412", "section508", "section508.22.a"},
+		Description: "Ensures buttons have discernible text",
+		Help:        "Buttons must have discernible text",
+		CheckID:     "button-has-visible-text",
+		check:       checkButtonName,
+	},
+	{
+		ID:          "label",
+		Impact:      "critical",
+		Tags:        []string{"cat.forms", "wcag2a", "wcag412", "section508", "section508.22.n"},
+		Description: "Ensures every form element has a label",
+		Help:        "Form elements must have labels",
+		CheckID:     "explicit-label",
+		check:       checkLabel,
+	},
+	{
+		ID:          "duplicate-id",
+		Impact:      "minor",
+		Tags:        []string{"cat.parsing", "wcag2a", "wcag411"},
+		Description: "Ensures every id attribute value is unique",
+		Help:        "id attribute value must be unique",
+		CheckID:     "duplicate-id",
+		check:       checkDuplicateID,
+	},
+	{
+		ID:          "heading-order",
+		Impact:      "moderate",
+		Tags:        []string{"cat.semantics", "best-practice"},
+		Description: "Ensures the order of headings is semantically correct",
+		Help:        "Heading levels should only increase by one",
+		CheckID:     "heading-order",
+		check:       checkHeadingOrder,
+	},
+	{
+		ID:          "th-has-data-cells",
+		Impact:      "serious",
+		Tags:        []string{"cat.tables", "wcag2a", "wcag131", "section508", "section508.22.g"},
+		Description: "Ensure that each table header in a data table refers to data cells",
+		Help:        "Table headers in a data table must refer to data cells",
+		CheckID:     "th-has-data-cells",
+		check:       checkTableHeaders,
+	},
+	{
+		ID:          "td-headers-attr",
+		Impact:      "serious",
+		Tags:        []string{"cat.tables", "wcag2a", "wcag131", "section508", "section508.22.g"},
+		Description: "Ensure that each cell in a table that uses the headers attribute refers only to other cells in that table",
+		Help:        "Table cells that use the headers attribute must only refer to cells in the same table",
+		CheckID:     "td-headers-attr",
+		check:       checkHeadersAttr,
+	},
+	{
+		ID:          "scope-attr-valid",
+		Impact:      "moderate",
+		Tags:        []string{"cat.tables", "best-practice"},
+		Description: "Ensures the scope attribute is used correctly on tables",
+		Help:        "scope attribute should be used correctly",
+		CheckID:     "scope-value",
+		check:       checkScopeAttr,
+	},
+}
+
+func pass(el *html.Node, message string) result {
+	return result{el: el, pass: true, message: message}
+}
+
+func fail(el *html.Node, message string) result {
+	return result{el: el, message: message}
+}
+
+func checkDocumentTitle(d *document) []result {
+	root := d.htmlElement()
+	if root == nil {
+		return nil
+	}
+	for _, title := range d.byTag(atom.Title) {
+		if closest(title, atom.Svg) == nil && strings.TrimSpace(visibleText(title)) != "" {
+			return []result{pass(root, "Document has a non-empty <title> element")}
+		}
+	}
+	return []result{fail(root, "Document does not have a non-empty <title> element")}
+}
+
+func checkHTMLLang(d *document) []result {
+	root := d.htmlElement()
+	if root == nil {
+		return nil
+	}
+	if attrValue(root, "lang") != "" || attrValue(root, "xml:lang") != "" {
+		return []result{pass(root, "The <html> element has a lang attribute")}
+	}
+	return []result{fail(root, "The <html> element does not have a lang attribute")}
+}
+
+func checkImageAlt(d *document) []result {
+	var out []result
+	for _, img := range d.byTag(atom.Img) {
+		if isHidden(img) || isAriaHidden(img) {
+			continue
+		}
+		switch {
+		case hasAttr(img, "alt"):
+			out = append(out, pass(img, "Element has an alt attribute"))
+		case d.ariaName(img) != "":
+			out = append(out, pass(img, "Element has an aria-label or aria-labelledby attribute"))
+		case attrValue(img, "title") != "":
+			out = append(out, pass(img, "Element has a title attribute"))
+		case hasRole(img, "none", "presentation"):
+			out = append(out, pass(img, "Element's default semantics were overridden with role=\"none\" or role=\"presentation\""))
+		default:
+			out = append(out, fail(img, "Element does not have an alt attribute, aria-label, aria-labelledby or title, and is not marked as presentational"))
+		}
+	}
+	return out
+}
+
+func checkInputImageAlt(d *document) []result {
+	var out []result
+	for _, input := range d.byTag(atom.Input) {
+		if !strings.EqualFold(attrValue(input, "type"), "image") || isHidden(input) || isAriaHidden(input) {
+			continue
+		}
+		if attrValue(input, "alt") != "" || d.ariaName(input) != "" || attrValue(input, "title") != "" {
+			out = append(out, pass(input, "Element has alternate text"))
+		} else {
+			out = append(out, fail(input, "Element has no alt attribute or the alt attribute is empty"))
+		}
+	}
+	return out
+}
+
+func checkLinkName(d *document) []result {
+	var out []result
+	for _, a := range d.byTag(atom.A) {
+		if !hasAttr(a, "href") || isHidden(a) || isAriaHidden(a) {
+			continue
+		}
+		if d.ariaName(a) != "" || visibleText(a) != "" || attrValue(a, "title") != "" {
+			out = append(out, pass(a, "Element has text that is visible to screen readers"))
+		} else {
+			out = append(out, fail(a, "Element does not have text that is visible to screen readers, an aria-label, aria-labelledby or title"))
+		}
+	}
+	return out
+}
+
+func checkButtonName(d *document) []result {
+	var out []result
+	for _, el := range d.elements {
+		var named, applies bool
+		switch {
+		case el.DataAtom == atom.Button:
+			applies = true
+			named = visibleText(el) != ""
+		case el.DataAtom == atom.Input:
+			switch strings.ToLower(attrValue(el, "type")) {
+			case "button":
+				applies = true
+				named = attrValue(el, "value") != ""
+			case "submit", "reset":
+				// Browsers give these a default label when value is missing.
+				_, hasValue := attr(el, "value")
+				applies = true
+				named = !hasValue || attrValue(el, "value") != ""
+			}
+		case hasRole(el, "button"):
+			applies = true
+			named = visibleText(el) != ""
+		}
+		if !applies || isHidden(el) || isAriaHidden(el) {
+			continue
+		}
+		if named || d.ariaName(el) != "" || attrValue(el, "title") != "" {
+			out = append(out, pass(el, "Element has inner text that is visible to screen readers"))
+		} else {
+			out = append(out, fail(el, "Element does not have inner text that is visible to screen readers, an aria-label, aria-labelledby or title"))
+		}
+	}
+	return out
+}
+
+// unlabelledInputTypes are input types that are labelled by their value or
+// are not form fields a user fills in.
+var unlabelledInputTypes = map[string]bool{
+	"hidden": true, "button": true, "submit": true, "reset": true, "image": true,
+}
+
+func checkLabel(d *document) []result {
+	labelFor := map[string]string{}
+	for _, label := range d.byTag(atom.Label) {
+		if id := attrValue(label, "for"); id != "" {
+			labelFor[id] += visibleText(label)
+		}
+	}
+	var out []result
+	for _, el := range d.byTag(atom.Input, atom.Select, atom.Textarea) {
+		if el.DataAtom == atom.Input && unlabelledInputTypes[strings.ToLower(attrValue(el, "type"))] {
+			continue
+		}
+		if isHidden(el) || isAriaHidden(el) || hasRole(el, "none", "presentation") {
+			continue
+		}
+		switch {
+		case labelFor[attrValue(el, "id")] != "":
+			out = append(out, pass(el, "Form element has an explicit <label>"))
+		case implicitLabel(el) != "":
+			out = append(out, pass(el, "Form element has an implicit (wrapped) <label>"))
+		case d.ariaName(el) != "":
+			out = append(out, pass(el, "Form element has an aria-label or aria-labelledby attribute"))
+		case attrValue(el, "title") != "":
+			out = append(out, pass(el, "Form element has a title attribute"))
+		case el.DataAtom != atom.Select && attrValue(el, "placeholder") != "":
+			out = append(out, pass(el, "Form element has a placeholder attribute"))
+		default:
+			out = append(out, fail(el, "Form element does not have an explicit <label>, an implicit (wrapped) <label>, aria-label, aria-labelledby, title or placeholder"))
+		}
+	}
+	return out
+}
+
+// implicitLabel returns the text of a <label> wrapping el, leaving out el's
+// own text such as a <select>'s options.
+func implicitLabel(el *html.Node) string {
+	label := closest(el, atom.Label)
+	if label == nil {
+		return ""
+	}
+	text := visibleText(label)
+	if own := visibleText(el); own != "" {
+		text = strings.TrimSpace(strings.Replace(text, own, "", 1))
+	}
+	return text
+}
+
+func checkDuplicateID(d *document) []result {
+	var out []result
+	for _, el := range d.elements {
+		id, ok := attr(el, "id")
+		if !ok || id == "" {
+			continue
+		}
+		same := d.ids[id]
+		if len(same) == 1 {
+			out = append(out, pass(el, "Document has no static elements that share the same id attribute"))
+			continue
+		}
+		if same[0] == el {
+			continue
+		}
+		res := fail(el, fmt.Sprintf("Document has multiple static elements with the same id attribute: %s", id))
+		res.related = []*html.Node{same[0]}
+		out = append(out, res)
+	}
+	return out
+}
+
+func headingLevel(el *html.Node) int {
+	if hasRole(el, "heading") {
+		if level, err := strconv.Atoi(attrValue(el, "aria-level")); err == nil && level > 0 {
+			return level
+		}
+		return 2
+	}
+	switch el.DataAtom {
+	case atom.H1:
+		return 1
+	case atom.H2:
+		return 2
+	case atom.H3:
+		return 3
+	case atom.H4:
+		return 4
+	case atom.H5:
+		return 5
+	case atom.H6:
+		return 6
+	}
+	return 0
+}
+
+func checkHeadingOrder(d *document) []result {
+	var out []result
+	var previous *html.Node
+	for _, el := range d.elements {
+		level := headingLevel(el)
+		if level == 0 || isHidden(el) || isAriaHidden(el) {
+			continue
+		}
+		if previous != nil && level > headingLevel(previous)+1 {
+			res := fail(el, fmt.Sprintf("Heading order invalid: h%d follows h%d", level, headingLevel(previous)))
+			res.related = []*html.Node{previous}
+			out = append(out, res)
+		} else {
+			out = append(out, pass(el, "Heading order valid"))
+		}
+		previous = el
+	}
+	return out
+}
+
+// isLayoutTable reports whether a table is marked as presentational, so its
+// cells are not data cells.
+func isLayoutTable(table *html.Node) bool {
+	return hasRole(table, "none", "presentation")
+}
+
+func checkTableHeaders(d *document) []result {
+	var out []result
+	for _, table := range d.byTag(atom.Table) {
+		if isLayoutTable(table) || isHidden(table) {
+			continue
+		}
+		headers := within(table, atom.Th)
+		if len(headers) == 0 {
+			continue
+		}
+		if len(within(table, atom.Td)) > 0 {
+			for _, th := range headers {
+				out = append(out, pass(th, "All table headers refer to data cells"))
+			}
+			continue
+		}
+		for _, th := range headers {
+			out = append(out, fail(th, "Table header does not refer to data cells"))
+		}
+	}
+	return out
+}
+
+func checkHeadersAttr(d *document) []result {
+	var out []result
+	for _, table := range d.byTag(atom.Table) {
+		if isLayoutTable(table) || isHidden(table) {
+			continue
+		}
+		cells := append(within(table, atom.Td), within(table, atom.Th)...)
+		inTable := map[string]bool{}
+		for _, cell := range cells {
+			if id := attrValue(cell, "id"); id != "" {
+				inTable[id] = true
+			}
+		}
+		for _, cell := range cells {
+			headers := strings.Fields(attrValue(cell, "headers"))
+			if len(headers) == 0 {
+				continue
+			}
+			var bad []string
+			for _, id := range headers {
+				if !inTable[id] || id == attrValue(cell, "id") {
+					bad = append(bad, id)
+				}
+			}
+			if len(bad) == 0 {
+				out = append(out, pass(cell, "The headers attribute is exclusively used to refer to other cells in the table"))
+			} else {
+				out = append(out, fail(cell, "The headers attribute does not refer to other cells in the table: "+strings.Join(bad, ", ")))
+			}
+		}
+	}
+	return out
+}
+
+var validScopes = map[string]bool{"row": true, "col": true, "rowgroup": true, "colgroup": true}
+
+func checkScopeAttr(d *document) []result {
+	var out []result
+	for _, el := range d.elements {
+		scope, ok := attr(el, "scope")
+		if !ok {
+			continue
+		}
+		switch {
+		case el.DataAtom != atom.Th:
+			out = append(out, fail(el, "The scope attribute should only be used on <th> elements"))
+		case !validScopes[strings.ToLower(strings.TrimSpace(scope))]:
+			out = append(out, fail(el, fmt.Sprintf("The scope attribute value %q is not one of row, col, rowgroup or colgroup", scope)))
+		default:
+			out = append(out, pass(el, "The scope attribute is used correctly"))
+		}
+	}
+	return out
+}
+
+func hasAttr(el *html.Node, name string) bool {
+	_, ok := attr(el, name)
+	return ok
+}
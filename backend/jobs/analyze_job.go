@@ -3,99 +3,267 @@ package jobs
 import (
 	"context"
 	"encoding/json"
-	"os/exec"
-	"strings"
+	"errors"
+	"fmt"
 
 	"backend/models"
 	"backend/services"
 	"backend/utils"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type AnalyzeJob struct {
 	ReportID primitive.ObjectID
+	UserID   primitive.ObjectID
 	URL      string
 	HTML     string
+	// CrawlID is set for the pages of a crawl.
+	CrawlID *primitive.ObjectID
+	// RescanOf is set instead of HTML when the page stored on another report
+	// is scanned again; it is loaded when the job runs.
+	RescanOf *primitive.ObjectID
+	// Auth holds credentials for pages behind a login. It is encrypted while
+	// the job is queued.
+	Auth    *models.ScanAuth
+	Options *models.ScanOptions
+	Device  *models.DeviceProfile
+	Engine  models.ScanEngine
 }
 
-var analyzeJobQueue = make(chan AnalyzeJob, 100)
-
-func EnqueueAnalyzeJob(job AnalyzeJob) {
-	analyzeJobQueue <- job
+// EnqueueAnalyzeJob persists the job in the jobs collection and returns as
+// soon as it is stored. Any worker, in this process or another, may pick it up.
+func EnqueueAnalyzeJob(ctx context.Context, job AnalyzeJob) error {
+	stored := &models.Job{
+		Kind:     models.JobKindAnalyze,
+		ReportID: job.ReportID,
+		CrawlID:  job.CrawlID,
+		RescanOf: job.RescanOf,
+		UserID:   job.UserID,
+		URL:      job.URL,
+		HTML:     job.HTML,
+		Options:  job.Options,
+		Device:   job.Device,
+		Engine:   job.Engine,
+	}
+	if !job.Auth.IsEmpty() {
+		sealed, err := sealScanAuth(job.Auth)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt scan credentials: %w", err)
+		}
+		stored.Auth, stored.HasAuth = sealed, true
+	}
+	if err := services.CreateJob(ctx, stored); err != nil {
+		return err
+	}
+	notifyWorkers()
+	return nil
 }
 
-func StartAnalyzeWorker() {
-	go func() {
-		for job := range analyzeJobQueue {
-			processAnalyzeJob(job)
+// ErrNotCancellable is returned when cancelling a report that has already
+// finished.
+var ErrNotCancellable = errors.New("report has already finished")
+
+// CancelAnalyzeJob moves an unfinished report to cancelled, drops its
+// queued job and stops the scan if it is running. Scans running in another
+// process stop at their next lease heartbeat. A crawl whose last page this
+// was is completed, since no worker will pick up the dropped job.
+func CancelAnalyzeJob(ctx context.Context, reportID primitive.ObjectID) error {
+	cancelled, err := services.CancelReport(ctx, reportID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrNotCancellable
+	}
+	if err := services.CancelJobsForReport(ctx, reportID); err != nil {
+		return err
+	}
+	inflight.cancel(reportID)
+	publishReport(reportID)
+	if report, err := services.GetReportByID(ctx, reportID); err == nil && report.CrawlID != nil {
+		if err := services.FinishCrawlIfDone(ctx, *report.CrawlID); err != nil {
+			return err
 		}
-	}()
+	}
+	return nil
 }
 
-func processAnalyzeJob(job AnalyzeJob) {
+// processAnalyzeJob runs one attempt of a scan. It returns errScanCancelled
+// when the scan was stopped from outside and a *ScanError when it failed; the
+// worker decides whether to retry.
+func processAnalyzeJob(ctx context.Context, job AnalyzeJob) error {
 	userID := "unknown"
 	// Try to fetch userId from report for logging
 	report, err := services.GetReportByID(context.Background(), job.ReportID)
 	if err == nil {
 		userID = report.UserID.Hex()
 	}
-	// Debug: log docker path (optional, keep for troubleshooting only)
-	// path, pathErr := exec.LookPath("docker")
-	// log.Printf("[DEBUG] Docker path: %s, err: %v", path, pathErr)
-	// if pathErr != nil {
-	// 	utils.LogAction(userID, "analyze", "failure", "docker not found: "+pathErr.Error())
-	// 	return
-	// }
-
-	input := make(map[string]string)
+	setStatus := func(status models.ReportStatus) {
+		if err := services.UpdateReportStatus(context.Background(), job.ReportID, status); err != nil {
+			utils.LogAction(userID, "analyze", "failure", "Failed to mark report "+string(status)+": "+err.Error())
+		}
+		publishReport(job.ReportID)
+	}
+	html := job.HTML
+	if job.RescanOf != nil && html == "" {
+		html, err = services.GetReportPage(context.Background(), *job.RescanOf)
+		if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && html == "") {
+			utils.LogAction(userID, "analyze", "failure", "No stored page to rescan for report "+job.ReportID.Hex())
+			return &ScanError{Err: errors.New("the report this rescans no longer has a stored page"), Retryable: false}
+		}
+		if err != nil {
+			utils.LogAction(userID, "analyze", "failure", "Failed to load page to rescan: "+err.Error())
+			return &ScanError{Err: fmt.Errorf("failed to load page to rescan: %w", err), Retryable: true}
+		}
+	}
 	if job.URL != "" {
-		input["url"] = job.URL
+		setStatus(models.ReportStatusFetching)
+	} else {
+		setStatus(models.ReportStatusScanning)
 	}
-	if job.HTML != "" {
-		input["html"] = job.HTML
+	output, err := runnerFor(job.Engine).Run(withPhaseReporter(ctx, setStatus), RunnerInput{
+		URL:         job.URL,
+		HTML:        html,
+		Auth:        job.Auth,
+		Axe:         axeRunFor(job.Options),
+		Options:     job.Options,
+		Device:      job.Device,
+		Screenshots: screenshotRequest(),
+		Snapshot:    job.URL != "",
+	})
+	// A cancel that lands as the runner finishes still drops the results: the
+	// report may have been deleted.
+	if errors.Is(ctx.Err(), context.Canceled) {
+		utils.LogAction(userID, "analyze", "cancelled", "Scan stopped for report "+job.ReportID.Hex())
+		return errScanCancelled
 	}
-	jsonInput, err := json.Marshal(input)
 	if err != nil {
-		utils.LogAction(userID, "analyze", "failure", "Failed to marshal input: "+err.Error())
-		_ = services.UpdateReportResults(context.Background(), job.ReportID, map[string]interface{}{"error": "Failed to marshal input"}, models.ReportStatusFailed)
-		return
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("scan timed out after %s", jobTimeout)
+		}
+		utils.LogAction(userID, "analyze", "failure", err.Error())
+		return classifyScanError(err)
 	}
-	cmd := exec.Command("docker", "run", "-i", "--rm", "axe-runner")
-	cmd.Stdin = strings.NewReader(string(jsonInput))
-	output, err := cmd.Output()
+	results, err := parseRunnerOutput(output)
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			utils.LogAction(userID, "analyze", "failure", "axe-runner stderr: "+string(exitErr.Stderr))
+		utils.LogAction(userID, "analyze", "failure", err.Error())
+		return err
+	}
+	services.FingerprintResults(results)
+	if report != nil {
+		if err := storeScreenshots(context.Background(), report, results, output); err != nil {
+			utils.LogAction(userID, "screenshot", "failure", "Failed to store screenshots: "+err.Error())
 		}
-		utils.LogAction(userID, "analyze", "failure", "axe-runner failed: "+err.Error())
-		_ = services.UpdateReportResults(context.Background(), job.ReportID, map[string]interface{}{"error": err.Error()}, models.ReportStatusFailed)
-		return
 	}
-	var results map[string]interface{}
-	err = json.Unmarshal(output, &results)
-	if err != nil {
-		utils.LogAction(userID, "analyze", "failure", "Invalid axe-runner output: "+err.Error())
-		_ = services.UpdateReportResults(context.Background(), job.ReportID, map[string]interface{}{"error": "Invalid axe-runner output"}, models.ReportStatusFailed)
-		return
+	if job.URL != "" {
+		if err := storeRenderedDOM(context.Background(), job.ReportID, output); err != nil {
+			utils.LogAction(userID, "snapshot", "failure", "Failed to store rendered page: "+err.Error())
+		}
 	}
-	err = services.UpdateReportResults(context.Background(), job.ReportID, results, models.ReportStatusComplete)
+	score := services.ComputeReportScore(results)
+	err = services.UpdateReportResults(context.Background(), job.ReportID, results, score, models.ReportStatusSuggesting)
 	if err != nil {
 		utils.LogAction(userID, "analyze", "failure", "Failed to update report: "+err.Error())
-		return
+		return &ScanError{Err: fmt.Errorf("failed to update report: %w", err), Retryable: true}
 	}
+	if report != nil {
+		if err := checkRegressions(report, results); err != nil {
+			utils.LogAction(userID, "baseline", "failure", "Failed to compare with baseline: "+err.Error())
+		}
+	}
+	publishReport(job.ReportID)
 	utils.LogAction(userID, "analyze", "success", "Analysis complete for report "+job.ReportID.Hex())
 	suggestions, err := services.GenerateSuggestionsFromLLM(results)
 	if err != nil {
 		utils.LogAction(userID, "llm_suggestion", "failure", "LLM error: "+err.Error())
+		_ = services.MarkReportPartial(context.Background(), job.ReportID, "Suggestions unavailable: "+err.Error())
+		publishReport(job.ReportID)
 	} else if len(suggestions) > 0 {
 		err2 := services.CreateSuggestion(context.Background(), job.ReportID, suggestions)
 		if err2 != nil {
 			utils.LogAction(userID, "llm_suggestion", "failure", "Failed to save suggestions: "+err2.Error())
+			_ = services.MarkReportPartial(context.Background(), job.ReportID, "Failed to save suggestions")
+			publishReport(job.ReportID)
 		} else {
 			utils.LogAction(userID, "llm_suggestion", "success", "Suggestions saved for report "+job.ReportID.Hex())
+			setStatus(models.ReportStatusComplete)
 		}
 	} else {
 		utils.LogAction(userID, "llm_suggestion", "failure", "No suggestions returned from LLM")
+		setStatus(models.ReportStatusComplete)
+	}
+	return nil
+}
+
+var snapshotMaxBytes = utils.EnvInt("SNAPSHOT_MAX_BYTES", 5<<20)
+
+// storeRenderedDOM keeps the rendered page from the runner's output on the
+// report. Pages larger than SNAPSHOT_MAX_BYTES are not kept.
+func storeRenderedDOM(ctx context.Context, reportID primitive.ObjectID, output []byte) error {
+	var parsed struct {
+		DOM string `json:"dom"`
+	}
+	if err := json.Unmarshal(output, &parsed); err != nil || parsed.DOM == "" {
+		return err
+	}
+	if len(parsed.DOM) > snapshotMaxBytes {
+		return fmt.Errorf("rendered page is %d bytes, over the %d byte limit", len(parsed.DOM), snapshotMaxBytes)
+	}
+	return services.SetReportDOM(ctx, reportID, parsed.DOM)
+}
+
+// checkRegressions compares fresh results with the baseline pinned for the
+// report's URL and scan settings, if any, and flags the report when new
+// violations appeared.
+func checkRegressions(report *models.Report, results *models.AxeResults) error {
+	if report.URL == "" {
+		return nil
+	}
+	ctx := context.Background()
+	baseline, err := services.GetBaseline(ctx, report)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var regressions []models.ViolationInstance
+	if baseline.ReportID != report.ID {
+		base, err := services.GetReportByID(ctx, baseline.ReportID)
+		if err != nil {
+			return fmt.Errorf("baseline report %s: %w", baseline.ReportID.Hex(), err)
+		}
+		if services.ScanVariant(base) != services.ScanVariant(report) {
+			// Pinned before baselines were keyed by scan settings.
+			return nil
+		}
+		scanned := *report
+		scanned.AnalysisResults = results
+		regressions = services.DiffReports(base, &scanned).Introduced
+	}
+	return services.SetReportRegressions(ctx, report.ID, baseline.ID, regressions)
+}
+
+// RequeueDeadJob moves one of the user's dead-lettered jobs back into the
+// queue with a fresh set of attempts.
+func RequeueDeadJob(ctx context.Context, jobID, userID primitive.ObjectID) (*models.Job, error) {
+	job, err := services.RequeueDeadJob(ctx, jobID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Kind == models.JobKindCrawl {
+		if err := services.UpdateCrawlStatus(ctx, *job.CrawlID, models.CrawlStatusQueued); err != nil {
+			return nil, err
+		}
+		notifyWorkers()
+		return job, nil
+	}
+	if err := services.UpdateReportStatus(ctx, job.ReportID, models.ReportStatusQueued); err != nil {
+		return nil, err
 	}
+	publishReport(job.ReportID)
+	notifyWorkers()
+	return job, nil
 }
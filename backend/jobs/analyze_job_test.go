@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"backend/models"
+	"backend/services"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDB points the services at a throwaway database on MONGODB_TEST_URI and
+// skips the test when it is not set.
+func testDB(t *testing.T) {
+	t.Helper()
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	db := client.Database(fmt.Sprintf("accessibility_analyser_test_%d", time.Now().UnixNano()))
+	services.InitReportService(db)
+	services.InitSuggestionService(db)
+	services.InitJobService(db)
+	services.InitBaselineService(db)
+	services.InitScreenshotService(db)
+	services.InitCrawlService(db)
+	t.Cleanup(func() {
+		_ = db.Drop(context.Background())
+		_ = client.Disconnect(context.Background())
+	})
+}
+
+// useRunner swaps the package runner for the length of a test.
+func useRunner(t *testing.T, r Runner) {
+	t.Helper()
+	previous := runner
+	SetRunner(r)
+	t.Cleanup(func() { SetRunner(previous) })
+}
+
+func TestProcessAnalyzeJob(t *testing.T) {
+	testDB(t)
+	t.Setenv("LLM_API_URL", "")
+	fake := &FakeRunner{}
+	useRunner(t, fake)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	report, err := services.CreateReport(ctx, userID, "", "<html><title>T</title></html>")
+	if err != nil {
+		t.Fatalf("CreateReport: %v", err)
+	}
+	opts := &models.ScanOptions{Standard: "wcag2a"}
+	err = processAnalyzeJob(ctx, AnalyzeJob{ReportID: report.ID, UserID: userID, HTML: report.HTMLSnapshot, Options: opts})
+	if err != nil {
+		t.Fatalf("processAnalyzeJob: %v", err)
+	}
+
+	if len(fake.Inputs) != 1 {
+		t.Fatalf("runner called %d times, want 1", len(fake.Inputs))
+	}
+	input := fake.Inputs[0]
+	if input.HTML != report.HTMLSnapshot || input.URL != "" || input.Snapshot {
+		t.Errorf("runner input = %+v, want the submitted html without a snapshot", input)
+	}
+	if input.Options != opts || input.Axe == nil {
+		t.Errorf("runner input options = %v, axe = %v, want the job's options", input.Options, input.Axe)
+	}
+
+	got, err := services.GetReportByID(ctx, report.ID)
+	if err != nil {
+		t.Fatalf("GetReportByID: %v", err)
+	}
+	if got.AnalysisResults == nil || len(got.AnalysisResults.Violations) != 1 || got.AnalysisResults.Violations[0].ID != "html-has-lang" {
+		t.Fatalf("stored results = %+v, want the fake runner's html-has-lang violation", got.AnalysisResults)
+	}
+	if got.AnalysisResults.Violations[0].Nodes[0].Fingerprint == "" {
+		t.Errorf("violation node was not fingerprinted")
+	}
+	// Without an LLM configured the scan finishes without suggestions.
+	if got.Status != models.ReportStatusPartiallyComplete {
+		t.Errorf("status = %s, want %s", got.Status, models.ReportStatusPartiallyComplete)
+	}
+}
+
+func TestProcessAnalyzeJobRunnerError(t *testing.T) {
+	testDB(t)
+	useRunner(t, &FakeRunner{Err: errors.New("net::ERR_NAME_NOT_RESOLVED at https://nowhere.invalid")})
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	report, err := services.CreateReport(ctx, userID, "https://nowhere.invalid", "")
+	if err != nil {
+		t.Fatalf("CreateReport: %v", err)
+	}
+	err = processAnalyzeJob(ctx, AnalyzeJob{ReportID: report.ID, UserID: userID, URL: report.URL})
+	var scanErr *ScanError
+	if !errors.As(err, &scanErr) {
+		t.Fatalf("processAnalyzeJob error = %v, want a *ScanError", err)
+	}
+	got, err := services.GetReportByID(ctx, report.ID)
+	if err != nil {
+		t.Fatalf("GetReportByID: %v", err)
+	}
+	if got.AnalysisResults != nil {
+		t.Errorf("results stored for a failed scan")
+	}
+}
+
+func TestProcessAnalyzeJobCancelled(t *testing.T) {
+	testDB(t)
+	useRunner(t, &FakeRunner{})
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	report, err := services.CreateReport(ctx, userID, "", "<html></html>")
+	if err != nil {
+		t.Fatalf("CreateReport: %v", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = processAnalyzeJob(cancelled, AnalyzeJob{ReportID: report.ID, UserID: userID, HTML: report.HTMLSnapshot})
+	if !errors.Is(err, errScanCancelled) {
+		t.Fatalf("processAnalyzeJob error = %v, want errScanCancelled", err)
+	}
+}
+
+func TestProcessAnalyzeJobRescan(t *testing.T) {
+	testDB(t)
+	fake := &FakeRunner{}
+	useRunner(t, fake)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	source, err := services.CreateReport(ctx, userID, "https://example.com/", "")
+	if err != nil {
+		t.Fatalf("CreateReport: %v", err)
+	}
+	page := "<html><body>rendered</body></html>"
+	if err := services.SetReportDOM(ctx, source.ID, page); err != nil {
+		t.Fatalf("SetReportDOM: %v", err)
+	}
+	rescan := services.NewReport(userID, source.URL, "")
+	rescan.RescanOf = &source.ID
+	if rescan, err = services.InsertReport(ctx, rescan); err != nil {
+		t.Fatalf("InsertReport: %v", err)
+	}
+	err = processAnalyzeJob(ctx, AnalyzeJob{ReportID: rescan.ID, UserID: userID, RescanOf: rescan.RescanOf})
+	if err != nil {
+		t.Fatalf("processAnalyzeJob: %v", err)
+	}
+	if len(fake.Inputs) != 1 || fake.Inputs[0].HTML != page || fake.Inputs[0].URL != "" {
+		t.Fatalf("runner inputs = %+v, want the source report's stored page", fake.Inputs)
+	}
+
+	// Without a stored page the rescan fails for good.
+	empty, err := services.CreateReport(ctx, userID, "", "")
+	if err != nil {
+		t.Fatalf("CreateReport: %v", err)
+	}
+	err = processAnalyzeJob(ctx, AnalyzeJob{ReportID: rescan.ID, UserID: userID, RescanOf: &empty.ID})
+	var scanErr *ScanError
+	if !errors.As(err, &scanErr) || scanErr.Retryable {
+		t.Errorf("processAnalyzeJob error = %v, want a permanent *ScanError", err)
+	}
+}
+
+func TestEnqueueAnalyzeJobOncePerReport(t *testing.T) {
+	testDB(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	report, err := services.CreateReport(ctx, userID, "", "<html></html>")
+	if err != nil {
+		t.Fatalf("CreateReport: %v", err)
+	}
+	job := AnalyzeJob{ReportID: report.ID, UserID: userID, HTML: report.HTMLSnapshot}
+	if err := EnqueueAnalyzeJob(ctx, job); err != nil {
+		t.Fatalf("EnqueueAnalyzeJob: %v", err)
+	}
+	if err := EnqueueAnalyzeJob(ctx, job); !errors.Is(err, services.ErrJobActive) {
+		t.Fatalf("second EnqueueAnalyzeJob error = %v, want ErrJobActive", err)
+	}
+	// Once the first job is settled the report can be queued again.
+	if err := services.CancelJobsForReport(ctx, report.ID); err != nil {
+		t.Fatalf("CancelJobsForReport: %v", err)
+	}
+	if err := EnqueueAnalyzeJob(ctx, job); err != nil {
+		t.Errorf("EnqueueAnalyzeJob after cancel: %v", err)
+	}
+	// Crawl jobs have no report and are not limited.
+	for i := 0; i < 2; i++ {
+		crawlID := primitive.NewObjectID()
+		if err := services.CreateJob(ctx, &models.Job{Kind: models.JobKindCrawl, CrawlID: &crawlID, UserID: userID}); err != nil {
+			t.Fatalf("CreateJob crawl %d: %v", i, err)
+		}
+	}
+}
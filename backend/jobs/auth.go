@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"backend/models"
+	"backend/utils"
+)
+
+// sealScanAuth encrypts scan credentials for storage on a job.
+func sealScanAuth(auth *models.ScanAuth) (string, error) {
+	data, err := json.Marshal(auth)
+	if err != nil {
+		return "", err
+	}
+	return utils.EncryptSecret(data)
+}
+
+// openScanAuth decrypts the credentials stored on a job, if it has any. A job
+// whose credentials can no longer be read, e.g. because SCAN_SECRETS_KEY
+// changed, fails for good rather than scanning the login page.
+func openScanAuth(job *models.Job) (*models.ScanAuth, error) {
+	if job.Auth == "" {
+		if job.HasAuth {
+			return nil, &ScanError{Err: fmt.Errorf("scan credentials are no longer available")}
+		}
+		return nil, nil
+	}
+	data, err := utils.DecryptSecret(job.Auth)
+	if err != nil {
+		return nil, &ScanError{Err: fmt.Errorf("failed to decrypt scan credentials: %w", err)}
+	}
+	var auth models.ScanAuth
+	if err := json.Unmarshal(data, &auth); err != nil {
+		return nil, &ScanError{Err: fmt.Errorf("failed to decode scan credentials: %w", err)}
+	}
+	return &auth, nil
+}
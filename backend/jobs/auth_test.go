@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"backend/models"
+	"backend/utils"
+)
+
+func TestScanAuthSealRoundTrip(t *testing.T) {
+	t.Setenv("SCAN_SECRETS_KEY", "0123456789abcdef0123456789abcdef")
+	auth := &models.ScanAuth{
+		Cookies: []models.ScanCookie{{Name: "session", Value: "abc"}},
+		Headers: map[string]string{"Authorization": "Bearer x"},
+	}
+	sealed, err := sealScanAuth(auth)
+	if err != nil {
+		t.Fatalf("sealScanAuth: %v", err)
+	}
+	if strings.Contains(sealed, "Bearer") {
+		t.Errorf("sealed credentials contain the plaintext")
+	}
+	got, err := openScanAuth(&models.Job{Auth: sealed, HasAuth: true})
+	if err != nil {
+		t.Fatalf("openScanAuth: %v", err)
+	}
+	if !reflect.DeepEqual(got, auth) {
+		t.Errorf("openScanAuth = %+v, want %+v", got, auth)
+	}
+}
+
+func TestOpenScanAuthFailures(t *testing.T) {
+	t.Setenv("SCAN_SECRETS_KEY", "0123456789abcdef0123456789abcdef")
+	sealed, err := sealScanAuth(&models.ScanAuth{Headers: map[string]string{"X": "y"}})
+	if err != nil {
+		t.Fatalf("sealScanAuth: %v", err)
+	}
+	if auth, err := openScanAuth(&models.Job{}); auth != nil || err != nil {
+		t.Errorf("job without credentials: openScanAuth = %v, %v", auth, err)
+	}
+	if _, err := openScanAuth(&models.Job{HasAuth: true}); err == nil || !strings.Contains(err.Error(), "no longer available") {
+		t.Errorf("job whose credentials were dropped: err = %v", err)
+	}
+
+	t.Setenv("SCAN_SECRETS_KEY", "")
+	_, err = openScanAuth(&models.Job{Auth: sealed, HasAuth: true})
+	var scanErr *ScanError
+	if !errors.As(err, &scanErr) || scanErr.Retryable || !errors.Is(err, utils.ErrSecretsKeyMissing) {
+		t.Errorf("missing key: err = %v, want a permanent ScanError wrapping ErrSecretsKeyMissing", err)
+	}
+	if _, err := sealScanAuth(&models.ScanAuth{}); !errors.Is(err, utils.ErrSecretsKeyMissing) {
+		t.Errorf("sealing without a key: err = %v, want ErrSecretsKeyMissing", err)
+	}
+}
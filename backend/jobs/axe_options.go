@@ -0,0 +1,53 @@
+package jobs
+
+import "backend/models"
+
+// AxeRun carries the arguments for axe.run(context, options) to the runner.
+type AxeRun struct {
+	Context map[string]interface{} `json:"context,omitempty"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// axeRunFor translates saved scan options into axe's own context and options
+// objects. It returns nil when axe should run with its defaults.
+func axeRunFor(opts *models.ScanOptions) *AxeRun {
+	if opts.IsEmpty() {
+		return nil
+	}
+	run := &AxeRun{}
+	// axe takes each selector as a list with one entry per frame.
+	frames := func(selectors []string) [][]string {
+		out := make([][]string, len(selectors))
+		for i, sel := range selectors {
+			out[i] = []string{sel}
+		}
+		return out
+	}
+	if len(opts.Include) > 0 || len(opts.Exclude) > 0 {
+		run.Context = map[string]interface{}{}
+		if len(opts.Include) > 0 {
+			run.Context["include"] = frames(opts.Include)
+		}
+		if len(opts.Exclude) > 0 {
+			run.Context["exclude"] = frames(opts.Exclude)
+		}
+	}
+	options := map[string]interface{}{}
+	if tags := opts.StandardTags(); len(tags) > 0 {
+		options["runOnly"] = map[string]interface{}{"type": "tag", "values": tags}
+	}
+	if len(opts.EnableRules) > 0 || len(opts.DisableRules) > 0 {
+		rules := map[string]interface{}{}
+		for _, id := range opts.EnableRules {
+			rules[id] = map[string]bool{"enabled": true}
+		}
+		for _, id := range opts.DisableRules {
+			rules[id] = map[string]bool{"enabled": false}
+		}
+		options["rules"] = rules
+	}
+	if len(options) > 0 {
+		run.Options = options
+	}
+	return run
+}
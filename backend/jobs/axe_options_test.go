@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"backend/models"
+)
+
+func TestAxeRunFor(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *models.ScanOptions
+		want string
+	}{
+		{"nil", nil, `null`},
+		{"empty", &models.ScanOptions{}, `null`},
+		{
+			"standard",
+			&models.ScanOptions{Standard: "wcag21aa", BestPractices: true},
+			`{"options":{"runOnly":{"type":"tag","values":["wcag2a","wcag2aa","wcag21a","wcag21aa","best-practice"]}}}`,
+		},
+		{
+			"rules",
+			&models.ScanOptions{EnableRules: []string{"region"}, DisableRules: []string{"color-contrast"}},
+			`{"options":{"rules":{"color-contrast":{"enabled":false},"region":{"enabled":true}}}}`,
+		},
+		{
+			"selectors",
+			&models.ScanOptions{Include: []string{"main"}, Exclude: []string{".ads", "#chat"}},
+			`{"context":{"exclude":[[".ads"],["#chat"]],"include":[["main"]]}}`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(axeRunFor(tt.opts))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: axeRunFor = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
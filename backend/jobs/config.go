@@ -0,0 +1,14 @@
+package jobs
+
+import (
+	"fmt"
+	"os"
+)
+
+func newWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "worker"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
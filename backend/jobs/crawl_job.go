@@ -0,0 +1,244 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+
+	"backend/crawler"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var crawlUserAgent = utils.EnvString("CRAWL_USER_AGENT", "AccessibilityAnalyzerBot/1.0")
+
+// maxSitemapURLs bounds how many URLs are read from one sitemap and its
+// children before deduplicating and filtering them.
+const maxSitemapURLs = 50000
+
+// StartCrawl stores a new crawl and queues its discovery job. A crawl whose
+// job could not be queued is marked failed.
+func StartCrawl(ctx context.Context, crawl *models.Crawl) error {
+	if err := services.CreateCrawl(ctx, crawl); err != nil {
+		return err
+	}
+	if err := EnqueueCrawlJob(ctx, crawl); err != nil {
+		_ = services.FailCrawl(context.Background(), crawl.ID, "Failed to enqueue job")
+		return err
+	}
+	return nil
+}
+
+// EnqueueCrawlJob queues discovery for a crawl that has been stored.
+func EnqueueCrawlJob(ctx context.Context, crawl *models.Crawl) error {
+	err := services.CreateJob(ctx, &models.Job{
+		Kind:    models.JobKindCrawl,
+		CrawlID: &crawl.ID,
+		UserID:  crawl.UserID,
+		URL:     crawl.StartURL,
+	})
+	if err != nil {
+		return err
+	}
+	notifyWorkers()
+	return nil
+}
+
+// CompilePatterns compiles include or exclude patterns of a crawl.
+func CompilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// processCrawlJob finds the crawl's pages, by following links from its start
+// URL or from its sitemap, and queues an analyze job for each. Running out of
+// time ends link discovery early but keeps the pages found so far.
+func processCrawlJob(ctx context.Context, job *models.Job) error {
+	crawl, err := services.GetCrawlByID(context.Background(), *job.CrawlID)
+	if err != nil {
+		return &ScanError{Err: fmt.Errorf("failed to load crawl: %w", err), Retryable: !errors.Is(err, mongo.ErrNoDocuments)}
+	}
+	userID := crawl.UserID.Hex()
+	include, err := CompilePatterns(crawl.Include)
+	if err != nil {
+		return &ScanError{Err: err}
+	}
+	exclude, err := CompilePatterns(crawl.Exclude)
+	if err != nil {
+		return &ScanError{Err: err}
+	}
+	if err := services.UpdateCrawlStatus(context.Background(), crawl.ID, models.CrawlStatusCrawling); err != nil {
+		return &ScanError{Err: fmt.Errorf("failed to update crawl: %w", err), Retryable: true}
+	}
+
+	found := 0
+	queue := func(pageURL string) error {
+		if err := queueCrawlPage(crawl, pageURL); err != nil {
+			return &ScanError{Err: fmt.Errorf("failed to queue %s: %w", pageURL, err), Retryable: true}
+		}
+		found++
+		_ = services.SetCrawlDiscovered(context.Background(), crawl.ID, found)
+		return nil
+	}
+	if crawl.Source == models.CrawlSourceSitemap {
+		var urls []string
+		urls, err = crawler.FetchSitemap(ctx, nil, crawlUserAgent, crawl.StartURL, maxSitemapURLs)
+		if err == nil {
+			pages, skipped := selectPages(urls, crawl.MaxPages, include, exclude)
+			_ = services.SetCrawlSkipped(context.Background(), crawl.ID, skipped)
+			for _, page := range pages {
+				if err = queue(page); err != nil {
+					break
+				}
+			}
+		}
+	} else {
+		opts := crawler.Options{
+			StartURL:  crawl.StartURL,
+			MaxPages:  crawl.MaxPages,
+			MaxDepth:  crawl.MaxDepth,
+			Include:   include,
+			Exclude:   exclude,
+			UserAgent: crawlUserAgent,
+		}
+		err = crawler.Crawl(ctx, opts, func(page crawler.Page) error { return queue(page.URL) })
+	}
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.Canceled):
+		return errScanCancelled
+	case errors.Is(ctx.Err(), context.DeadlineExceeded) && found > 0:
+		utils.LogAction(userID, "crawl", "success", fmt.Sprintf("Crawl %s stopped after %s with %d pages", crawl.ID.Hex(), crawlTimeout, found))
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return classifyScanError(fmt.Errorf("crawl timed out after %s", crawlTimeout))
+	default:
+		utils.LogAction(userID, "crawl", "failure", err.Error())
+		return classifyScanError(err)
+	}
+	return finishDiscovery(crawl, found)
+}
+
+// StartURLListCrawl queues a scan for each URL of an uploaded list on a crawl
+// that has been stored, then hands the crawl over to its scans.
+func StartURLListCrawl(ctx context.Context, crawl *models.Crawl, urls []string) error {
+	for _, u := range urls {
+		if err := queueCrawlPage(crawl, u); err != nil {
+			return err
+		}
+	}
+	if err := services.SetCrawlDiscovered(ctx, crawl.ID, len(urls)); err != nil {
+		return err
+	}
+	return finishDiscovery(crawl, len(urls))
+}
+
+// SelectListPages normalises and deduplicates uploaded URLs the way sitemap
+// URLs are, and returns those the crawl will scan.
+func SelectListPages(urls []string, maxPages int, include, exclude []string) (pages []string, skipped int, err error) {
+	inc, err := CompilePatterns(include)
+	if err != nil {
+		return nil, 0, err
+	}
+	exc, err := CompilePatterns(exclude)
+	if err != nil {
+		return nil, 0, err
+	}
+	pages, skipped = selectPages(urls, maxPages, inc, exc)
+	return pages, skipped, nil
+}
+
+// selectPages normalises and deduplicates urls, drops those the patterns
+// rule out and keeps at most maxPages.
+func selectPages(urls []string, maxPages int, include, exclude []*regexp.Regexp) (pages []string, skipped int) {
+	normalized, skipped := services.NormalizeURLs(urls)
+	filter := crawler.Options{Include: include, Exclude: exclude}
+	for _, u := range normalized {
+		if len(pages) >= maxPages || !filter.Allowed(u) {
+			skipped++
+			continue
+		}
+		pages = append(pages, u)
+	}
+	return pages, skipped
+}
+
+func finishDiscovery(crawl *models.Crawl, found int) error {
+	if err := services.UpdateCrawlStatus(context.Background(), crawl.ID, models.CrawlStatusScanning); err != nil {
+		return &ScanError{Err: fmt.Errorf("failed to update crawl: %w", err), Retryable: true}
+	}
+	utils.LogAction(crawl.UserID.Hex(), "crawl", "success", fmt.Sprintf("Crawl %s found %d pages", crawl.ID.Hex(), found))
+	// Every page may already have been scanned by the time discovery ends.
+	return services.FinishCrawlIfDone(context.Background(), crawl.ID)
+}
+
+// queueCrawlPage creates the report for a page and queues its scan. A page
+// found again when a crawl job is re-run is only queued if its earlier job
+// never made it into the queue.
+func queueCrawlPage(crawl *models.Crawl, pageURL string) error {
+	ctx := context.Background()
+	report, created, err := services.CreateCrawlReport(ctx, crawl.UserID, crawl.ID, pageURL)
+	if err != nil {
+		return err
+	}
+	if !created {
+		if report.Status != models.ReportStatusQueued {
+			return nil
+		}
+		active, err := services.HasActiveJob(ctx, report.ID)
+		if err != nil || active {
+			return err
+		}
+	}
+	return EnqueueAnalyzeJob(ctx, AnalyzeJob{
+		ReportID: report.ID,
+		UserID:   crawl.UserID,
+		URL:      report.URL,
+		CrawlID:  &crawl.ID,
+	})
+}
+
+// settleCrawlJob acks, retries or dead-letters a crawl job the same way
+// finishJob does for scans.
+func settleCrawlJob(job *models.Job, owner string, err error, interrupted bool) {
+	ctx := context.Background()
+	crawlID := *job.CrawlID
+	var ackErr error
+	switch {
+	case interrupted:
+		ackErr = services.ReleaseJob(ctx, job.ID, owner)
+		_ = services.UpdateCrawlStatus(ctx, crawlID, models.CrawlStatusQueued)
+	case err == nil:
+		ackErr = services.CompleteJob(ctx, job.ID, owner)
+	case errors.Is(err, errScanCancelled):
+	default:
+		scanErr := classifyScanError(err)
+		if scanErr.Retryable && job.Attempts < maxAttempts {
+			ackErr = services.RetryJob(ctx, job.ID, owner, retryDelay(job.Attempts), scanErr.Error())
+			_ = services.UpdateCrawlStatus(ctx, crawlID, models.CrawlStatusQueued)
+			break
+		}
+		reason := scanErr.Error()
+		if scanErr.Retryable {
+			reason = fmt.Sprintf("gave up after %d attempts: %s", job.Attempts, reason)
+		}
+		ackErr = services.DeadLetterJob(ctx, job.ID, owner, reason)
+		if ackErr == nil {
+			_ = services.FailCrawl(ctx, crawlID, reason)
+		}
+	}
+	if ackErr != nil && !errors.Is(ackErr, services.ErrJobLeaseLost) {
+		log.Printf("[jobs] failed to settle crawl job %s: %v", job.ID.Hex(), ackErr)
+	}
+}
@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"errors"
+	"strings"
+)
+
+// ScanError is a failed scan together with whether trying again might help.
+type ScanError struct {
+	Err       error
+	Retryable bool
+}
+
+func (e *ScanError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// errScanCancelled is returned when a scan stopped because its report was
+// cancelled or its lease was taken over; neither is the scan's fault.
+var errScanCancelled = errors.New("scan cancelled")
+
+// Messages that point at the target page itself rather than at the network
+// or the runner, so retrying would only fail the same way.
+var permanentErrorMarkers = []string{
+	"must provide url or html",
+	"invalid url",
+	"err_invalid_url",
+	"err_unsafe_port",
+	"err_blocked_by_client",
+	"err_cert_",
+	"err_ssl_",
+	"err_too_many_redirects",
+	"err_file_not_found",
+	// axe rejecting the scan's own options, e.g. a rule id it does not know.
+	"unknown rule",
+	"no elements found for include",
+	"is not a valid selector",
+}
+
+// classifyScanError wraps err in a ScanError. Failures are retryable unless
+// they match a known permanent cause: DNS blips, resets, navigation timeouts
+// and runner crashes are all worth another attempt.
+func classifyScanError(err error) *ScanError {
+	var scanErr *ScanError
+	if errors.As(err, &scanErr) {
+		return scanErr
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range permanentErrorMarkers {
+		if strings.Contains(msg, marker) {
+			return &ScanError{Err: err, Retryable: false}
+		}
+	}
+	return &ScanError{Err: err, Retryable: true}
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClassifyScanError(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{errors.New("net::ERR_NAME_NOT_RESOLVED at https://nowhere.invalid"), true},
+		{errors.New("net::ERR_CONNECTION_RESET"), true},
+		{errors.New("Navigation timeout of 30000 ms exceeded"), true},
+		{errors.New("axe-runner failed: signal: killed"), true},
+		{errors.New("Must provide URL or HTML"), false},
+		{errors.New("net::ERR_CERT_AUTHORITY_INVALID at https://self-signed.example"), false},
+		{errors.New("net::ERR_TOO_MANY_REDIRECTS"), false},
+		{errors.New("unknown rule `no-such-rule` in options.rules"), false},
+		{errors.New("'main >' is not a valid selector"), false},
+		{fmt.Errorf("axe-runner failed: %w", errors.New("Invalid URL")), false},
+	}
+	for _, tt := range tests {
+		got := classifyScanError(tt.err)
+		if got.Retryable != tt.retryable {
+			t.Errorf("classifyScanError(%q).Retryable = %v, want %v", tt.err, got.Retryable, tt.retryable)
+		}
+		if !errors.Is(got, tt.err) {
+			t.Errorf("classifyScanError(%q) does not wrap the original error", tt.err)
+		}
+	}
+}
+
+func TestClassifyScanErrorKeepsScanError(t *testing.T) {
+	original := &ScanError{Err: errors.New("invalid url"), Retryable: true}
+	if got := classifyScanError(fmt.Errorf("wrapped: %w", original)); got != original {
+		t.Errorf("classifyScanError re-classified an existing ScanError: %+v", got)
+	}
+	if errors.Is(classifyScanError(context.DeadlineExceeded), errScanCancelled) {
+		t.Errorf("a timeout counts as a cancellation")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	defer func(backoff, max time.Duration) { retryBackoff, retryBackoffMax = backoff, max }(retryBackoff, retryBackoffMax)
+	retryBackoff, retryBackoffMax = 30*time.Second, 10*time.Minute
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{6, 10 * time.Minute},
+		{40, 10 * time.Minute},
+		// A shift past the width of a Duration must not wrap to zero.
+		{70, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			got := retryDelay(tt.attempt)
+			if got < tt.base || got > tt.base+tt.base/5 {
+				t.Fatalf("retryDelay(%d) = %s, want between %s and %s", tt.attempt, got, tt.base, tt.base+tt.base/5)
+			}
+		}
+	}
+}
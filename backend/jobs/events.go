@@ -0,0 +1,148 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"backend/models"
+	"backend/services"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReportEvent is a status change of a report as seen by live subscribers.
+// Summary is only set once the report has reached a final status.
+type ReportEvent struct {
+	ReportID primitive.ObjectID  `json:"reportId"`
+	Status   models.ReportStatus `json:"status"`
+	Progress int                 `json:"progress"`
+	At       time.Time           `json:"at"`
+	Error    string              `json:"error,omitempty"`
+	Summary  *ReportSummary      `json:"summary,omitempty"`
+}
+
+// ReportSummary sums up a finished report.
+type ReportSummary struct {
+	Violations   int `json:"violations"`
+	Passes       int `json:"passes"`
+	Incomplete   int `json:"incomplete"`
+	Inapplicable int `json:"inapplicable"`
+}
+
+// NewReportEvent describes the current state of report.
+func NewReportEvent(report *models.Report) ReportEvent {
+	ev := ReportEvent{
+		ReportID: report.ID,
+		Status:   report.Status,
+		Progress: report.Progress,
+		At:       report.UpdatedAt,
+		Error:    report.Error,
+	}
+	if report.Status.IsFinal() {
+		ev.Summary = summarizeReport(report)
+	}
+	return ev
+}
+
+func summarizeReport(report *models.Report) *ReportSummary {
+	results := report.AnalysisResults
+	if results == nil {
+		return &ReportSummary{}
+	}
+	return &ReportSummary{
+		Violations:   len(results.Violations),
+		Passes:       len(results.Passes),
+		Incomplete:   len(results.Incomplete),
+		Inapplicable: len(results.Inapplicable),
+	}
+}
+
+// eventHub fans report events out to subscribers in this process.
+type eventHub struct {
+	mu     sync.Mutex
+	subs   map[primitive.ObjectID]map[chan ReportEvent]struct{}
+	closed bool
+}
+
+var events = &eventHub{subs: make(map[primitive.ObjectID]map[chan ReportEvent]struct{})}
+
+// SubscribeReport returns a channel of events for reportID and a function
+// that ends the subscription. The channel is closed when the subscription
+// ends or the server shuts down.
+func SubscribeReport(reportID primitive.ObjectID) (<-chan ReportEvent, func()) {
+	ch := make(chan ReportEvent, 16)
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if events.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if events.subs[reportID] == nil {
+		events.subs[reportID] = make(map[chan ReportEvent]struct{})
+	}
+	events.subs[reportID][ch] = struct{}{}
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			events.mu.Lock()
+			defer events.mu.Unlock()
+			if _, ok := events.subs[reportID][ch]; !ok {
+				return
+			}
+			delete(events.subs[reportID], ch)
+			if len(events.subs[reportID]) == 0 {
+				delete(events.subs, reportID)
+			}
+			close(ch)
+		})
+	}
+}
+
+// CloseEventStreams ends every subscription so open streams let the HTTP
+// server shut down.
+func CloseEventStreams() {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	events.closed = true
+	for reportID, subs := range events.subs {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(events.subs, reportID)
+	}
+}
+
+func (h *eventHub) hasSubscribers(reportID primitive.ObjectID) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[reportID]) > 0
+}
+
+func (h *eventHub) publish(ev ReportEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[ev.ReportID] {
+		select {
+		case ch <- ev:
+		default:
+			// A slow reader misses intermediate events; the final state is
+			// still picked up by the stream's periodic refresh.
+		}
+	}
+}
+
+// publishReport reloads a report after the pipeline changed it and tells any
+// subscribers about its new state.
+func publishReport(reportID primitive.ObjectID) {
+	if !events.hasSubscribers(reportID) {
+		return
+	}
+	report, err := services.GetReportByID(context.Background(), reportID)
+	if err != nil {
+		log.Printf("[jobs] failed to load report %s for events: %v", reportID.Hex(), err)
+		return
+	}
+	events.publish(NewReportEvent(report))
+}
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"backend/models"
+)
+
+// parseRunnerOutput checks what axe-runner printed before it is stored. The
+// runner reports page failures in-band as {"error": true, "message": ...};
+// those become scan errors so they are retried or failed like any other.
+func parseRunnerOutput(output []byte) (*models.AxeResults, error) {
+	var runnerErr struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(output, &runnerErr); err != nil {
+		return nil, &ScanError{Err: fmt.Errorf("Invalid axe-runner output: %w", err), Retryable: false}
+	}
+	if runnerErr.Error {
+		msg := runnerErr.Message
+		if msg == "" {
+			msg = "axe-runner reported an error without a message"
+		}
+		return nil, classifyScanError(errors.New(msg))
+	}
+
+	var results models.AxeResults
+	if err := json.Unmarshal(output, &results); err != nil {
+		return nil, &ScanError{Err: fmt.Errorf("Invalid axe-runner output: %w", err), Retryable: false}
+	}
+	if results.TestEngine.Name == "" && results.Violations == nil && results.Passes == nil {
+		return nil, &ScanError{Err: errors.New("Invalid axe-runner output: not an axe-core result"), Retryable: false}
+	}
+	for _, list := range []*[]models.AxeRule{&results.Violations, &results.Passes, &results.Incomplete, &results.Inapplicable} {
+		if *list == nil {
+			*list = []models.AxeRule{}
+		}
+	}
+	return &results, nil
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseRunnerOutput(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		wantErr   string
+		retryable bool
+	}{
+		{"runner error", `{"error":true,"message":"net::ERR_CONNECTION_RESET"}`, "net::ERR_CONNECTION_RESET", true},
+		{"runner error not retryable", `{"error":true,"message":"Must provide URL or HTML"}`, "Must provide URL or HTML", false},
+		{"runner error without message", `{"error":true}`, "axe-runner reported an error without a message", true},
+		{"empty", ``, "Invalid axe-runner output", false},
+		{"not json", `Error: Cannot find module 'puppeteer'`, "Invalid axe-runner output", false},
+		{"empty object", `{}`, "not an axe-core result", false},
+		{"other json", `{"status":"ok"}`, "not an axe-core result", false},
+		{"wrong shape", `{"violations":"none"}`, "Invalid axe-runner output", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := parseRunnerOutput([]byte(tt.output))
+			if results != nil {
+				t.Errorf("results = %+v, want nil", results)
+			}
+			var scanErr *ScanError
+			if !errors.As(err, &scanErr) {
+				t.Fatalf("err = %v, want a *ScanError", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %q, want it to contain %q", err, tt.wantErr)
+			}
+			if scanErr.Retryable != tt.retryable {
+				t.Errorf("Retryable = %v, want %v", scanErr.Retryable, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestParseRunnerOutputResults(t *testing.T) {
+	output := `{
+		"testEngine": {"name": "axe-core", "version": "4.10.2"},
+		"url": "https://example.com/",
+		"violations": [{
+			"id": "image-alt",
+			"impact": "critical",
+			"tags": ["wcag2a", "wcag111"],
+			"nodes": [{"html": "<img src=\"a.png\">", "target": ["img"], "any": [], "all": [], "none": []}]
+		}],
+		"passes": [{"id": "html-has-lang", "nodes": []}]
+	}`
+	results, err := parseRunnerOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("parseRunnerOutput: %v", err)
+	}
+	if results.TestEngine.Name != "axe-core" || results.TestEngine.Version != "4.10.2" {
+		t.Errorf("TestEngine = %+v", results.TestEngine)
+	}
+	if results.URL != "https://example.com/" {
+		t.Errorf("URL = %q", results.URL)
+	}
+	if len(results.Violations) != 1 || results.Violations[0].ID != "image-alt" || results.Violations[0].Impact != "critical" {
+		t.Fatalf("Violations = %+v", results.Violations)
+	}
+	if nodes := results.Violations[0].Nodes; len(nodes) != 1 || nodes[0].HTML != `<img src="a.png">` {
+		t.Errorf("Nodes = %+v", nodes)
+	}
+	if len(results.Passes) != 1 || results.Passes[0].ID != "html-has-lang" {
+		t.Errorf("Passes = %+v", results.Passes)
+	}
+	// Missing lists decode as empty, not null, so stored reports always have them.
+	if results.Incomplete == nil || results.Inapplicable == nil {
+		t.Errorf("Incomplete = %v, Inapplicable = %v, want empty lists", results.Incomplete, results.Inapplicable)
+	}
+}
+
+func TestParseRunnerOutputEmptyRun(t *testing.T) {
+	results, err := parseRunnerOutput([]byte(`{"testEngine":{"name":"axe-core"}}`))
+	if err != nil {
+		t.Fatalf("parseRunnerOutput: %v", err)
+	}
+	if results.Violations == nil || len(results.Violations) != 0 {
+		t.Errorf("Violations = %v, want an empty list", results.Violations)
+	}
+}
@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"backend/models"
+)
+
+type phaseReporterKey struct{}
+
+// withPhaseReporter attaches fn to ctx so runners can say when a scan moves
+// from loading the page to running axe.
+func withPhaseReporter(ctx context.Context, fn func(models.ReportStatus)) context.Context {
+	return context.WithValue(ctx, phaseReporterKey{}, fn)
+}
+
+// reportPhase passes a phase name emitted by axe-runner ("fetching" or
+// "scanning") to the reporter on ctx, if any. Unknown names are ignored.
+func reportPhase(ctx context.Context, phase string) {
+	fn, ok := ctx.Value(phaseReporterKey{}).(func(models.ReportStatus))
+	if !ok {
+		return
+	}
+	switch status := models.ReportStatus(strings.TrimSpace(phase)); status {
+	case models.ReportStatusFetching, models.ReportStatusScanning:
+		fn(status)
+	}
+}
+
+// phaseWriter collects a runner's stderr, picking out "phase:<name>" lines
+// and keeping everything else for error messages.
+type phaseWriter struct {
+	ctx     context.Context
+	partial []byte
+	stderr  bytes.Buffer
+}
+
+func (w *phaseWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.line(w.partial[:i+1])
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *phaseWriter) line(line []byte) {
+	if phase, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("phase:")); ok {
+		reportPhase(w.ctx, string(phase))
+		return
+	}
+	w.stderr.Write(line)
+}
+
+func (w *phaseWriter) String() string {
+	return w.stderr.String() + string(w.partial)
+}
@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+)
+
+var (
+	staleAfter    = utils.EnvDuration("REPORT_STALE_AFTER", 15*time.Minute)
+	maxRequeueAge = utils.EnvDuration("REPORT_MAX_REQUEUE_AGE", 24*time.Hour)
+)
+
+// RecoverOrphanedReports looks for reports that have sat in an unfinished
+// state for longer than REPORT_STALE_AFTER with no live job behind them, which
+// happens when the process died between creating a report and finishing its
+// scan. Recent ones are queued again; anything older than
+// REPORT_MAX_REQUEUE_AGE, or without input to re-run, is marked failed.
+func RecoverOrphanedReports(ctx context.Context) (requeued, failed int, err error) {
+	reports, err := services.FindStaleReports(ctx, models.ActiveReportStatuses, time.Now().Add(-staleAfter))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, report := range reports {
+		active, err := services.HasActiveJob(ctx, report.ID)
+		if err != nil {
+			return requeued, failed, err
+		}
+		if active {
+			// The queue still owns it; an expired lease will be picked up again.
+			continue
+		}
+		reason := ""
+		switch {
+		case report.URL == "" && report.HTMLSnapshot == "" && report.RescanOf == nil:
+			reason = "Scan was interrupted and the report has no URL or HTML to re-run"
+		case report.Authenticated:
+			reason = "Scan was interrupted and its login details are not kept, so it was not retried"
+		case time.Since(report.CreatedAt) > maxRequeueAge:
+			reason = fmt.Sprintf("Scan was interrupted and is older than %s, so it was not retried", maxRequeueAge)
+		}
+		if reason != "" {
+			if err := services.FailReport(ctx, report.ID, reason); err != nil {
+				return requeued, failed, err
+			}
+			failed++
+			continue
+		}
+		url := report.URL
+		if report.RescanOf != nil {
+			// Rescans keep the URL for reference but scan the stored page.
+			url = ""
+		}
+		err = EnqueueAnalyzeJob(ctx, AnalyzeJob{
+			ReportID: report.ID,
+			UserID:   report.UserID,
+			URL:      url,
+			HTML:     report.HTMLSnapshot,
+			CrawlID:  report.CrawlID,
+			RescanOf: report.RescanOf,
+			Options:  report.ScanOptions,
+			Device:   report.Device,
+			Engine:   report.Engine,
+		})
+		if errors.Is(err, services.ErrJobActive) {
+			// Another process queued it since HasActiveJob was checked.
+			continue
+		}
+		if err != nil {
+			return requeued, failed, err
+		}
+		if err := services.UpdateReportStatus(ctx, report.ID, models.ReportStatusQueued); err != nil {
+			return requeued, failed, err
+		}
+		requeued++
+	}
+	if requeued > 0 || failed > 0 {
+		log.Printf("[jobs] recovered orphaned reports: %d requeued, %d failed", requeued, failed)
+	}
+	return requeued, failed, nil
+}
+
+// RecoverStalledCrawls completes crawls left scanning with no page still in
+// progress, e.g. because their last page was cancelled or the process died
+// before the crawl was checked again. Run it after RecoverOrphanedReports so
+// pages that were failed there count as finished.
+func RecoverStalledCrawls(ctx context.Context) error {
+	ids, err := services.ListCrawlIDsByStatus(ctx, models.CrawlStatusScanning)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := services.FinishCrawlIfDone(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"backend/models"
+	"backend/utils"
+)
+
+// RunnerInput is the document handed to axe-runner on stdin or in the body of
+// an HTTP request.
+type RunnerInput struct {
+	URL  string           `json:"url,omitempty"`
+	HTML string           `json:"html,omitempty"`
+	Auth *models.ScanAuth `json:"auth,omitempty"`
+	Axe  *AxeRun          `json:"axe,omitempty"`
+	// Device sets the viewport and user agent; the runner's default is used
+	// when nil.
+	Device *models.DeviceProfile `json:"device,omitempty"`
+	// Screenshots asks for a full-page image and crops of violating elements.
+	Screenshots *ScreenshotRequest `json:"screenshots,omitempty"`
+	// Snapshot asks for the rendered page to be returned as "dom".
+	Snapshot bool `json:"snapshot,omitempty"`
+	// Options are the scan options Axe was built from, for runners that do
+	// not run axe itself.
+	Options *models.ScanOptions `json:"-"`
+}
+
+type ScreenshotRequest struct {
+	// MaxNodes caps the number of element crops.
+	MaxNodes int `json:"maxNodes"`
+}
+
+// Runner executes axe-core against a page and returns the raw JSON it printed.
+type Runner interface {
+	Run(ctx context.Context, input RunnerInput) ([]byte, error)
+}
+
+var runner Runner = NewDockerRunner(utils.EnvString("AXE_RUNNER_IMAGE", "axe-runner"))
+
+// SetRunner replaces the runner used by the analysis workers.
+func SetRunner(r Runner) {
+	runner = r
+}
+
+// NewRunnerFromEnv builds the runner named by AXE_RUNNER: docker (default),
+// node, http or fake.
+func NewRunnerFromEnv() (Runner, error) {
+	kind := strings.ToLower(utils.EnvString("AXE_RUNNER", "docker"))
+	switch kind {
+	case "docker":
+		return NewDockerRunner(utils.EnvString("AXE_RUNNER_IMAGE", "axe-runner")), nil
+	case "node":
+		return NewNodeRunner(utils.EnvString("NODE_BIN", "node"), utils.EnvString("AXE_RUNNER_SCRIPT", "axe-runner/axe-runner.js")), nil
+	case "http":
+		r := NewHTTPRunner(utils.EnvString("AXE_RUNNER_URL", "http://localhost:3001"))
+		// The service may still be starting; scans will be retried, so only warn.
+		if health, err := r.Health(context.Background()); err != nil {
+			log.Printf("[jobs] axe-runner service not ready: %v", err)
+		} else if health.Pool.Size < poolSize {
+			log.Printf("[jobs] axe-runner service has %d pages for %d workers; scans will queue in the runner", health.Pool.Size, poolSize)
+		}
+		return r, nil
+	case "fake":
+		if path := os.Getenv("AXE_RUNNER_FAKE_FILE"); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("read fake runner output: %w", err)
+			}
+			return &FakeRunner{Output: data}, nil
+		}
+		return &FakeRunner{}, nil
+	default:
+		return nil, fmt.Errorf("unknown AXE_RUNNER %q", kind)
+	}
+}
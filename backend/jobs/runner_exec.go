@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DockerRunner starts a throwaway axe-runner container for every scan.
+type DockerRunner struct {
+	Image string
+}
+
+func NewDockerRunner(image string) *DockerRunner {
+	return &DockerRunner{Image: image}
+}
+
+func (r *DockerRunner) Run(ctx context.Context, input RunnerInput) ([]byte, error) {
+	name := "axe-runner-" + primitive.NewObjectID().Hex()
+	cmd := exec.CommandContext(ctx, "docker", "run", "-i", "--rm", "--name", name, r.Image)
+	// Killing the docker CLI leaves the container running, so stop the
+	// container itself when the scan is cancelled or times out.
+	cmd.Cancel = func() error {
+		_ = exec.Command("docker", "kill", name).Run()
+		return cmd.Process.Kill()
+	}
+	return runCommand(ctx, cmd, input)
+}
+
+// NodeRunner runs axe-runner.js with a local node binary, for machines
+// without Docker.
+type NodeRunner struct {
+	Node   string
+	Script string
+}
+
+func NewNodeRunner(node, script string) *NodeRunner {
+	return &NodeRunner{Node: node, Script: script}
+}
+
+func (r *NodeRunner) Run(ctx context.Context, input RunnerInput) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, r.Node, filepath.Base(r.Script))
+	cmd.Dir = filepath.Dir(r.Script)
+	// node starts Chromium as a child; kill the whole group on cancel.
+	killProcessGroupOnCancel(cmd)
+	return runCommand(ctx, cmd, input)
+}
+
+func runCommand(ctx context.Context, cmd *exec.Cmd, input RunnerInput) ([]byte, error) {
+	jsonInput, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+	stderr := &phaseWriter{ctx: ctx}
+	cmd.Stdin = bytes.NewReader(jsonInput)
+	cmd.Stderr = stderr
+	cmd.WaitDelay = 5 * time.Second
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); errors.As(err, &exitErr) && msg != "" {
+			return nil, fmt.Errorf("axe-runner failed: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("axe-runner failed: %w", err)
+	}
+	return output, nil
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package jobs
+
+import "os/exec"
+
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+)
+
+// fakeAxeOutput is a trimmed axe-core result with one violation and one pass.
+const fakeAxeOutput = `{
+  "testEngine": {"name": "axe-core", "version": "4.8.2"},
+  "testRunner": {"name": "axe"},
+  "url": "about:blank",
+  "timestamp": "2024-01-01T00:00:00.000Z",
+  "violations": [{
+    "id": "html-has-lang",
+    "impact": "serious",
+    "tags": ["cat.language", "wcag2a", "wcag311"],
+    "description": "Ensures every HTML document has a lang attribute",
+    "help": "<html> element must have a lang attribute",
+    "helpUrl": "https://dequeuniversity.com/rules/axe/4.8/html-has-lang",
+    "nodes": [{
+      "html": "<html>",
+      "impact": "serious",
+      "target": ["html"],
+      "any": [{"id": "has-lang", "impact": "serious", "message": "The <html> element does not have a lang attribute", "data": {"messageKey": "noLang"}, "relatedNodes": []}],
+      "all": [],
+      "none": [],
+      "failureSummary": "Fix any of the following:\n  The <html> element does not have a lang attribute"
+    }]
+  }],
+  "passes": [{
+    "id": "document-title",
+    "impact": null,
+    "tags": ["cat.text-alternatives", "wcag2a", "wcag242"],
+    "description": "Ensures each HTML document contains a non-empty <title> element",
+    "help": "Documents must have <title> element to aid in navigation",
+    "helpUrl": "https://dequeuniversity.com/rules/axe/4.8/document-title",
+    "nodes": [{"html": "<html>", "impact": null, "target": ["html"], "any": [], "all": [], "none": []}]
+  }],
+  "incomplete": [],
+  "inapplicable": []
+}`
+
+// FakeRunner returns canned axe output without touching a browser. Output
+// defaults to a small built-in result; Err, when set, is returned instead.
+type FakeRunner struct {
+	Output []byte
+	Err    error
+
+	mu     sync.Mutex
+	Inputs []RunnerInput
+}
+
+func (r *FakeRunner) Run(ctx context.Context, input RunnerInput) ([]byte, error) {
+	r.mu.Lock()
+	r.Inputs = append(r.Inputs, input)
+	r.mu.Unlock()
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	reportPhase(ctx, "scanning")
+	if r.Output != nil {
+		return r.Output, nil
+	}
+	return []byte(fakeAxeOutput), nil
+}
@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPRunner sends scans to the long-lived axe-runner service
+// (axe-runner/server.js), which keeps Chromium and a pool of pages warm
+// between requests. The service accepts POST /analyze with a RunnerInput body
+// and reports pool usage on GET /healthz.
+type HTTPRunner struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// RunnerHealth is the body returned by the service's /healthz endpoint.
+type RunnerHealth struct {
+	Status  string `json:"status"`
+	Browser bool   `json:"browser"`
+	Pool    struct {
+		Size    int `json:"size"`
+		Busy    int `json:"busy"`
+		Idle    int `json:"idle"`
+		Waiting int `json:"waiting"`
+	} `json:"pool"`
+}
+
+func NewHTTPRunner(baseURL string) *HTTPRunner {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Every worker talks to the same host, so keep a connection per worker.
+	transport.MaxIdleConnsPerHost = poolSize
+	return &HTTPRunner{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Transport: transport},
+	}
+}
+
+func (r *HTTPRunner) Run(ctx context.Context, input RunnerInput) ([]byte, error) {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.BaseURL+"/analyze", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Ask for phase updates ahead of the result; older services ignore this
+	// and answer with the plain result.
+	req.Header.Set("Accept", "application/x-ndjson, application/json")
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("axe-runner service unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		output, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("axe-runner service error: %s: %s", resp.Status, strings.TrimSpace(string(output)))
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/x-ndjson") {
+		output, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read axe-runner response: %w", err)
+		}
+		return output, nil
+	}
+	return readRunnerStream(ctx, resp.Body)
+}
+
+// readRunnerStream reads newline-delimited messages of the form
+// {"phase": "..."} followed by a final {"result": ...}.
+func readRunnerStream(ctx context.Context, body io.Reader) ([]byte, error) {
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var msg struct {
+				Phase  string          `json:"phase"`
+				Result json.RawMessage `json:"result"`
+			}
+			if jsonErr := json.Unmarshal(line, &msg); jsonErr != nil {
+				return nil, fmt.Errorf("invalid axe-runner stream message: %w", jsonErr)
+			}
+			if msg.Result != nil {
+				return msg.Result, nil
+			}
+			reportPhase(ctx, msg.Phase)
+		}
+		if err == io.EOF {
+			return nil, errors.New("axe-runner service closed the stream without a result")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read axe-runner response: %w", err)
+		}
+	}
+}
+
+// Health asks the service whether its browser is up and how busy its page
+// pool is.
+func (r *HTTPRunner) Health(ctx context.Context) (*RunnerHealth, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.BaseURL+"/healthz", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("axe-runner service unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("axe-runner service unhealthy: %s", resp.Status)
+	}
+	var health RunnerHealth
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("invalid axe-runner health response: %w", err)
+	}
+	return &health, nil
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"backend/htmlcheck"
+	"backend/models"
+)
+
+// StaticRunner checks submitted HTML with the htmlcheck package instead of a
+// browser. It understands the standard and rule selection of ScanOptions but
+// not include or exclude selectors.
+type StaticRunner struct{}
+
+func (StaticRunner) Run(ctx context.Context, input RunnerInput) ([]byte, error) {
+	if input.HTML == "" {
+		return nil, errors.New("the static engine only checks submitted html")
+	}
+	opts, err := htmlcheckOptions(input.Options)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	results, err := htmlcheck.Check(strings.NewReader(input.HTML), input.URL, opts)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(results)
+}
+
+// htmlcheckOptions maps saved scan options onto the static checker's.
+func htmlcheckOptions(scan *models.ScanOptions) (htmlcheck.Options, error) {
+	opts := htmlcheck.Options{}
+	if scan.IsEmpty() {
+		return opts, nil
+	}
+	if len(scan.Include) > 0 || len(scan.Exclude) > 0 {
+		return opts, errors.New("include and exclude are not supported by the static engine")
+	}
+	opts.Tags = scan.StandardTags()
+	if len(scan.EnableRules) > 0 || len(scan.DisableRules) > 0 {
+		opts.Rules = map[string]bool{}
+		for _, id := range scan.EnableRules {
+			opts.Rules[id] = true
+		}
+		for _, id := range scan.DisableRules {
+			opts.Rules[id] = false
+		}
+	}
+	return opts, nil
+}
+
+// runnerFor returns the runner for a job's engine.
+func runnerFor(engine models.ScanEngine) Runner {
+	if engine == models.ScanEngineStatic {
+		return StaticRunner{}
+	}
+	return runner
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"backend/models"
+)
+
+func TestStaticRunner(t *testing.T) {
+	input := RunnerInput{
+		HTML:    `<html><head><title>T</title></head><body><img src="a.png"></body></html>`,
+		Options: &models.ScanOptions{Standard: "wcag2a", DisableRules: []string{"html-has-lang"}},
+	}
+	output, err := StaticRunner{}.Run(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	results, err := parseRunnerOutput(output)
+	if err != nil {
+		t.Fatalf("parseRunnerOutput: %v\n%s", err, output)
+	}
+	var violations []string
+	for _, r := range results.Violations {
+		violations = append(violations, r.ID)
+	}
+	if len(violations) != 1 || violations[0] != "image-alt" {
+		t.Errorf("violations = %v, want [image-alt]", violations)
+	}
+}
+
+func TestStaticRunnerRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		input RunnerInput
+	}{
+		{"url only", RunnerInput{URL: "https://example.com/"}},
+		{"include", RunnerInput{HTML: "<p>", Options: &models.ScanOptions{Include: []string{"main"}}}},
+		{"unknown rule", RunnerInput{HTML: "<p>", Options: &models.ScanOptions{EnableRules: []string{"color-contrast"}}}},
+	}
+	for _, tt := range tests {
+		if _, err := (StaticRunner{}).Run(context.Background(), tt.input); err == nil {
+			t.Errorf("%s: Run succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestHTMLCheckOptions(t *testing.T) {
+	opts, err := htmlcheckOptions(&models.ScanOptions{
+		Standard:      "wcag21aa",
+		BestPractices: true,
+		EnableRules:   []string{"label"},
+		DisableRules:  []string{"heading-order"},
+	})
+	if err != nil {
+		t.Fatalf("htmlcheckOptions: %v", err)
+	}
+	tags, _ := json.Marshal(opts.Tags)
+	if string(tags) != `["wcag2a","wcag2aa","wcag21a","wcag21aa","best-practice"]` {
+		t.Errorf("tags = %s", tags)
+	}
+	if on, ok := opts.Rules["label"]; !ok || !on {
+		t.Errorf("label not enabled: %v", opts.Rules)
+	}
+	if on, ok := opts.Rules["heading-order"]; !ok || on {
+		t.Errorf("heading-order not disabled: %v", opts.Rules)
+	}
+	if opts, err := htmlcheckOptions(nil); err != nil || opts.Tags != nil || opts.Rules != nil {
+		t.Errorf("htmlcheckOptions(nil) = %+v, %v, want defaults", opts, err)
+	}
+}
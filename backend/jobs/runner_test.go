@@ -0,0 +1,280 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"backend/models"
+)
+
+// phaseRecorder collects the phases a runner reports through ctx.
+type phaseRecorder struct {
+	mu     sync.Mutex
+	phases []models.ReportStatus
+}
+
+func (p *phaseRecorder) context() context.Context {
+	return withPhaseReporter(context.Background(), func(s models.ReportStatus) {
+		p.mu.Lock()
+		p.phases = append(p.phases, s)
+		p.mu.Unlock()
+	})
+}
+
+func (p *phaseRecorder) got() []models.ReportStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]models.ReportStatus(nil), p.phases...)
+}
+
+func TestHTTPRunner(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		status      int
+		body        string
+		want        string
+		wantErr     string
+		wantPhases  []models.ReportStatus
+	}{
+		{
+			name:        "plain result",
+			contentType: "application/json",
+			body:        `{"violations":[]}`,
+			want:        `{"violations":[]}`,
+		},
+		{
+			name:        "stream with phases",
+			contentType: "application/x-ndjson",
+			body:        "{\"phase\":\"fetching\"}\n\n{\"phase\":\"bogus\"}\n{\"phase\":\"scanning\"}\n{\"result\":{\"violations\":[]}}\n",
+			want:        `{"violations":[]}`,
+			wantPhases:  []models.ReportStatus{models.ReportStatusFetching, models.ReportStatusScanning},
+		},
+		{
+			name:        "stream result without trailing newline",
+			contentType: "application/x-ndjson",
+			body:        `{"result":{"error":true,"message":"boom"}}`,
+			want:        `{"error":true,"message":"boom"}`,
+		},
+		{
+			name:        "stream without result",
+			contentType: "application/x-ndjson",
+			body:        "{\"phase\":\"fetching\"}\n",
+			wantErr:     "without a result",
+			wantPhases:  []models.ReportStatus{models.ReportStatusFetching},
+		},
+		{
+			name:        "invalid stream message",
+			contentType: "application/x-ndjson",
+			body:        "not json\n",
+			wantErr:     "invalid axe-runner stream message",
+		},
+		{
+			name:        "service error",
+			contentType: "text/plain",
+			status:      http.StatusServiceUnavailable,
+			body:        "pool closed\n",
+			wantErr:     "503 Service Unavailable: pool closed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotInput string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/analyze" {
+					http.NotFound(w, r)
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				gotInput = string(body)
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			phases := &phaseRecorder{}
+			out, err := NewHTTPRunner(srv.URL+"/").Run(phases.context(), RunnerInput{URL: "https://example.com/"})
+			switch {
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Run error = %v, want it to contain %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("Run: %v", err)
+			case string(out) != tt.want:
+				t.Errorf("Run = %s, want %s", out, tt.want)
+			}
+			if !strings.Contains(gotInput, `"url":"https://example.com/"`) {
+				t.Errorf("service got input %s", gotInput)
+			}
+			if got := phases.got(); !reflect.DeepEqual(got, tt.wantPhases) {
+				t.Errorf("phases = %v, want %v", got, tt.wantPhases)
+			}
+		})
+	}
+}
+
+func TestHTTPRunnerHealth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok","browser":true,"pool":{"size":4,"busy":1,"idle":3,"waiting":0}}`))
+	}))
+	defer srv.Close()
+	health, err := NewHTTPRunner(srv.URL).Health(context.Background())
+	if err != nil {
+		t.Fatalf("Health: %v", err)
+	}
+	if health.Status != "ok" || !health.Browser || health.Pool.Size != 4 || health.Pool.Idle != 3 {
+		t.Errorf("Health = %+v", health)
+	}
+}
+
+func TestPhaseWriter(t *testing.T) {
+	phases := &phaseRecorder{}
+	w := &phaseWriter{ctx: phases.context()}
+	for _, chunk := range []string{"phase:fetch", "ing\nwarning: slow page\nphase: scanning\n", "phase:unknown\ntrailing"} {
+		if n, err := w.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	want := []models.ReportStatus{models.ReportStatusFetching, models.ReportStatusScanning}
+	if got := phases.got(); !reflect.DeepEqual(got, want) {
+		t.Errorf("phases = %v, want %v", got, want)
+	}
+	if got := w.String(); got != "warning: slow page\ntrailing" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	tests := []struct {
+		name       string
+		script     string
+		want       string
+		wantErr    string
+		wantPhases []models.ReportStatus
+	}{
+		{
+			name:       "echoes input and reports phases",
+			script:     `echo phase:fetching >&2; echo phase:scanning >&2; cat`,
+			want:       `{"html":"hi"}`,
+			wantPhases: []models.ReportStatus{models.ReportStatusFetching, models.ReportStatusScanning},
+		},
+		{
+			name:       "failure keeps stderr without phases",
+			script:     `echo phase:fetching >&2; echo 'Error: net::ERR_NAME_NOT_RESOLVED' >&2; exit 3`,
+			wantErr:    "exit status 3: Error: net::ERR_NAME_NOT_RESOLVED",
+			wantPhases: []models.ReportStatus{models.ReportStatusFetching},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phases := &phaseRecorder{}
+			ctx := phases.context()
+			out, err := runCommand(ctx, exec.CommandContext(ctx, "sh", "-c", tt.script), RunnerInput{HTML: "hi"})
+			switch {
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || strings.Contains(err.Error(), "phase:") {
+					t.Fatalf("runCommand error = %v, want it to contain %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("runCommand: %v", err)
+			case strings.TrimSpace(string(out)) != tt.want:
+				t.Errorf("runCommand = %s, want %s", out, tt.want)
+			}
+			if got := phases.got(); !reflect.DeepEqual(got, tt.wantPhases) {
+				t.Errorf("phases = %v, want %v", got, tt.wantPhases)
+			}
+		})
+	}
+}
+
+func TestNewRunnerFromEnv(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok","browser":true,"pool":{"size":8}}`))
+	}))
+	defer srv.Close()
+	fakeFile := filepath.Join(t.TempDir(), "axe.json")
+	if err := os.WriteFile(fakeFile, []byte(`{"violations":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("AXE_RUNNER", "")
+	t.Setenv("AXE_RUNNER_IMAGE", "custom-image")
+	r, err := NewRunnerFromEnv()
+	if d, ok := r.(*DockerRunner); err != nil || !ok || d.Image != "custom-image" {
+		t.Errorf("default runner = %#v, %v, want a docker runner for custom-image", r, err)
+	}
+
+	t.Setenv("AXE_RUNNER", "Node")
+	t.Setenv("NODE_BIN", "/usr/bin/node")
+	r, err = NewRunnerFromEnv()
+	if n, ok := r.(*NodeRunner); err != nil || !ok || n.Node != "/usr/bin/node" || n.Script != "axe-runner/axe-runner.js" {
+		t.Errorf("node runner = %#v, %v", r, err)
+	}
+
+	t.Setenv("AXE_RUNNER", "http")
+	t.Setenv("AXE_RUNNER_URL", srv.URL+"/")
+	r, err = NewRunnerFromEnv()
+	if h, ok := r.(*HTTPRunner); err != nil || !ok || h.BaseURL != srv.URL {
+		t.Errorf("http runner = %#v, %v", r, err)
+	}
+
+	t.Setenv("AXE_RUNNER", "fake")
+	t.Setenv("AXE_RUNNER_FAKE_FILE", fakeFile)
+	r, err = NewRunnerFromEnv()
+	if f, ok := r.(*FakeRunner); err != nil || !ok || string(f.Output) != `{"violations":[]}` {
+		t.Errorf("fake runner = %#v, %v", r, err)
+	}
+
+	t.Setenv("AXE_RUNNER_FAKE_FILE", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := NewRunnerFromEnv(); err == nil {
+		t.Errorf("fake runner with a missing file succeeded")
+	}
+
+	t.Setenv("AXE_RUNNER", "lambda")
+	if _, err := NewRunnerFromEnv(); err == nil || !strings.Contains(err.Error(), "unknown AXE_RUNNER") {
+		t.Errorf("unknown runner error = %v", err)
+	}
+}
+
+func TestFakeRunner(t *testing.T) {
+	fake := &FakeRunner{}
+	out, err := fake.Run(context.Background(), RunnerInput{HTML: "<p>"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	results, err := parseRunnerOutput(out)
+	if err != nil {
+		t.Fatalf("built-in output does not parse: %v", err)
+	}
+	if len(results.Violations) != 1 || len(fake.Inputs) != 1 || fake.Inputs[0].HTML != "<p>" {
+		t.Errorf("results = %+v, inputs = %+v", results, fake.Inputs)
+	}
+
+	boom := &FakeRunner{Err: fmt.Errorf("boom")}
+	if _, err := boom.Run(context.Background(), RunnerInput{}); err == nil || err.Error() != "boom" {
+		t.Errorf("Run error = %v, want boom", err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fake.Run(cancelled, RunnerInput{}); err == nil {
+		t.Errorf("Run on a cancelled context succeeded")
+	}
+}
@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	schedulePollInterval = utils.EnvDuration("SCHEDULE_POLL_INTERVAL", 30*time.Second)
+	// A run that starts later than this after it was due counts as missed,
+	// e.g. because the server was down, and follows the schedule's
+	// MissedRunPolicy.
+	missedRunGrace = utils.EnvDuration("SCHEDULE_MISSED_RUN_GRACE", 15*time.Minute)
+	scheduler      = &scheduleLoop{}
+)
+
+// dueScheduleBatch is how many due schedules one poll picks up.
+const dueScheduleBatch = 50
+
+type scheduleLoop struct {
+	mu   sync.Mutex
+	quit chan struct{}
+	done chan struct{}
+}
+
+// StartScheduler starts the loop that runs due schedules. Every instance may
+// run one; each run is claimed atomically so it only starts once.
+func StartScheduler() {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.quit != nil {
+		return
+	}
+	scheduler.quit = make(chan struct{})
+	scheduler.done = make(chan struct{})
+	go func() {
+		defer close(scheduler.done)
+		ticker := time.NewTicker(schedulePollInterval)
+		defer ticker.Stop()
+		for {
+			runDueSchedules(context.Background(), time.Now())
+			select {
+			case <-scheduler.quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	log.Printf("[jobs] scheduler polling every %s", schedulePollInterval)
+}
+
+// StopScheduler stops the scheduler and waits for the poll in progress, if
+// any, to finish.
+func StopScheduler() {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if scheduler.quit == nil {
+		return
+	}
+	close(scheduler.quit)
+	<-scheduler.done
+	scheduler.quit = nil
+}
+
+func runDueSchedules(ctx context.Context, now time.Time) {
+	schedules, err := services.DueSchedules(ctx, now, dueScheduleBatch)
+	if err != nil {
+		log.Printf("[jobs] failed to load due schedules: %v", err)
+		return
+	}
+	for i := range schedules {
+		schedule := &schedules[i]
+		// The next run is worked out from now rather than from the missed
+		// one, so downtime never causes a burst of back-to-back runs.
+		next, err := services.ScheduleNext(schedule.Cron, schedule.Timezone, now)
+		if err != nil {
+			log.Printf("[jobs] schedule %s: %v", schedule.ID.Hex(), err)
+			continue
+		}
+		claimed, err := services.ClaimScheduleRun(ctx, schedule, next)
+		if err != nil {
+			log.Printf("[jobs] failed to claim schedule %s: %v", schedule.ID.Hex(), err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if now.Sub(schedule.NextRunAt) > missedRunGrace && schedule.MissedRunPolicy == models.MissedRunSkip {
+			utils.LogAction(schedule.UserID.Hex(), "schedule", "skipped", fmt.Sprintf("Missed run of schedule %s due at %s", schedule.ID.Hex(), schedule.NextRunAt.Format(time.RFC3339)))
+			if err := services.RecordSkippedRun(ctx, schedule.ID); err != nil {
+				log.Printf("[jobs] failed to record skipped run of schedule %s: %v", schedule.ID.Hex(), err)
+			}
+			continue
+		}
+		runSchedule(ctx, schedule)
+	}
+}
+
+// runSchedule starts the scan or crawl a schedule describes.
+func runSchedule(ctx context.Context, schedule *models.Schedule) {
+	var reportID, crawlID *primitive.ObjectID
+	var err error
+	if schedule.Crawl != nil {
+		cfg := schedule.Crawl
+		crawl := &models.Crawl{
+			UserID:     schedule.UserID,
+			Source:     cfg.Source,
+			ScheduleID: &schedule.ID,
+			StartURL:   cfg.StartURL,
+			Domain:     services.URLDomain(cfg.StartURL),
+			MaxPages:   cfg.MaxPages,
+			MaxDepth:   cfg.MaxDepth,
+			Include:    cfg.Include,
+			Exclude:    cfg.Exclude,
+		}
+		if err = StartCrawl(ctx, crawl); crawl.ID != primitive.NilObjectID {
+			crawlID = &crawl.ID
+		}
+	} else {
+		var report *models.Report
+		report, err = services.CreateScheduledReport(ctx, schedule.UserID, schedule.ID, schedule.URL)
+		if err == nil {
+			reportID = &report.ID
+			err = EnqueueAnalyzeJob(ctx, AnalyzeJob{ReportID: report.ID, UserID: schedule.UserID, URL: schedule.URL})
+			if err != nil {
+				_ = services.FailReport(ctx, report.ID, "Failed to enqueue job")
+			}
+		}
+	}
+	if err != nil {
+		utils.LogAction(schedule.UserID.Hex(), "schedule", "failure", fmt.Sprintf("Schedule %s failed to start: %v", schedule.ID.Hex(), err))
+	} else {
+		utils.LogAction(schedule.UserID.Hex(), "schedule", "success", "Started run of schedule "+schedule.ID.Hex())
+	}
+	if err := services.RecordScheduleRun(ctx, schedule.ID, reportID, crawlID, err); err != nil {
+		log.Printf("[jobs] failed to record run of schedule %s: %v", schedule.ID.Hex(), err)
+	}
+}
@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	screenshotsEnabled = strings.ToLower(utils.EnvString("SCAN_SCREENSHOTS", "true")) != "false"
+	screenshotMaxNodes = utils.EnvInt("SCREENSHOT_MAX_NODES", 25)
+)
+
+func screenshotRequest() *ScreenshotRequest {
+	if !screenshotsEnabled {
+		return nil
+	}
+	return &ScreenshotRequest{MaxNodes: screenshotMaxNodes}
+}
+
+// runnerScreenshots is the screenshots entry axe-runner adds to its results.
+type runnerScreenshots struct {
+	Page  string `json:"page"`
+	Nodes []struct {
+		Rule  string `json:"rule"`
+		Node  int    `json:"node"`
+		Image string `json:"image"`
+	} `json:"nodes"`
+}
+
+// storeScreenshots saves the images in the runner's output and links them
+// from the report and its violation nodes. Images left by an earlier attempt
+// are removed first.
+func storeScreenshots(ctx context.Context, report *models.Report, results *models.AxeResults, output []byte) error {
+	var parsed struct {
+		Screenshots *runnerScreenshots `json:"screenshots"`
+	}
+	if err := json.Unmarshal(output, &parsed); err != nil || parsed.Screenshots == nil {
+		return err
+	}
+	if err := services.DeleteScreenshotsForReport(ctx, report.ID); err != nil {
+		return err
+	}
+	shots := parsed.Screenshots
+	var pageID *primitive.ObjectID
+	if shots.Page != "" {
+		data, err := base64.StdEncoding.DecodeString(shots.Page)
+		if err != nil {
+			return fmt.Errorf("invalid page screenshot: %w", err)
+		}
+		id, err := services.SaveScreenshot(ctx, report.ID, "page.jpg", "image/jpeg", data)
+		if err != nil {
+			return err
+		}
+		pageID = &id
+	}
+	if err := services.SetReportScreenshot(ctx, report.ID, pageID); err != nil {
+		return err
+	}
+	rules := make(map[string]*models.AxeRule, len(results.Violations))
+	for i := range results.Violations {
+		rules[results.Violations[i].ID] = &results.Violations[i]
+	}
+	for _, shot := range shots.Nodes {
+		rule, ok := rules[shot.Rule]
+		if !ok || shot.Node < 0 || shot.Node >= len(rule.Nodes) {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(shot.Image)
+		if err != nil {
+			return fmt.Errorf("invalid screenshot for %s: %w", shot.Rule, err)
+		}
+		name := fmt.Sprintf("%s-%d.png", shot.Rule, shot.Node)
+		id, err := services.SaveScreenshot(ctx, report.ID, name, "image/png", data)
+		if err != nil {
+			return err
+		}
+		rule.Nodes[shot.Node].Screenshot = &id
+	}
+	return nil
+}
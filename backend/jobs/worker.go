@@ -0,0 +1,368 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	processID       = newWorkerID()
+	poolSize        = utils.EnvInt("ANALYZE_WORKERS", 4)
+	perUserLimit    = utils.EnvInt("ANALYZE_WORKERS_PER_USER", 1)
+	leaseDuration   = utils.EnvDuration("JOB_VISIBILITY_TIMEOUT", 5*time.Minute)
+	pollInterval    = utils.EnvDuration("JOB_POLL_INTERVAL", 2*time.Second)
+	jobTimeout      = utils.EnvDuration("ANALYZE_JOB_TIMEOUT", 2*time.Minute)
+	crawlTimeout    = utils.EnvDuration("CRAWL_JOB_TIMEOUT", 10*time.Minute)
+	maxAttempts     = utils.EnvInt("ANALYZE_MAX_ATTEMPTS", 3)
+	retryBackoff    = utils.EnvDuration("ANALYZE_RETRY_BACKOFF", 30*time.Second)
+	retryBackoffMax = utils.EnvDuration("ANALYZE_RETRY_BACKOFF_MAX", 10*time.Minute)
+	wakeWorker      = make(chan struct{}, 1)
+	workers         = &workerPool{}
+	inflight        = &inflightJobs{cancels: make(map[primitive.ObjectID]context.CancelFunc)}
+
+	// Heartbeats extend the lease and notice cancellation from other
+	// processes, so they run well inside the lease.
+	heartbeatInterval = min(utils.EnvDuration("JOB_HEARTBEAT_INTERVAL", 10*time.Second), leaseDuration/3)
+)
+
+// PoolStats describes the analysis workers running in this process.
+type PoolStats struct {
+	Size int `json:"size"`
+	Busy int `json:"busy"`
+	Idle int `json:"idle"`
+}
+
+type workerPool struct {
+	mu   sync.Mutex
+	size int
+	busy int
+
+	// quit stops workers from claiming new jobs; cancel interrupts the scans
+	// they are running. wg tracks the worker goroutines.
+	quit     chan struct{}
+	stopOnce sync.Once
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	wg       sync.WaitGroup
+}
+
+func (p *workerPool) setBusy(delta int) {
+	p.mu.Lock()
+	p.busy += delta
+	p.mu.Unlock()
+}
+
+func (p *workerPool) stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{Size: p.size, Busy: p.busy, Idle: p.size - p.busy}
+}
+
+// errWorkerShutdown is the cancellation cause for scans interrupted because
+// the process is shutting down.
+var errWorkerShutdown = errors.New("worker shutting down")
+
+// StartAnalyzeWorkers starts ANALYZE_WORKERS goroutines that pull jobs from
+// the durable queue.
+func StartAnalyzeWorkers() {
+	workers.mu.Lock()
+	workers.size = poolSize
+	workers.quit = make(chan struct{})
+	workers.ctx, workers.cancel = context.WithCancelCause(context.Background())
+	workers.mu.Unlock()
+	for i := 0; i < poolSize; i++ {
+		owner := fmt.Sprintf("%s/%d", processID, i)
+		workers.wg.Add(1)
+		go func() {
+			defer workers.wg.Done()
+			runWorker(owner)
+		}()
+	}
+	log.Printf("[jobs] started %d analysis workers (%d per user)", poolSize, perUserLimit)
+}
+
+// StopAnalyzeWorkers stops claiming new jobs and waits for running scans to
+// finish. When ctx expires first, the remaining scans are interrupted and
+// their jobs put back in the queue without using up an attempt, so another
+// instance picks them up straight away.
+func StopAnalyzeWorkers(ctx context.Context) error {
+	if workers.quit == nil {
+		return nil
+	}
+	workers.stopOnce.Do(func() { close(workers.quit) })
+	finished := make(chan struct{})
+	go func() {
+		workers.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+	}
+	workers.cancel(errWorkerShutdown)
+	select {
+	case <-finished:
+		return fmt.Errorf("grace period expired, interrupted %d running scans", workers.stats().Busy)
+	case <-time.After(10 * time.Second):
+		return errors.New("workers did not stop after their scans were interrupted")
+	}
+}
+
+// notifyWorkers wakes one idle worker in this process instead of letting it
+// wait for the next poll.
+func notifyWorkers() {
+	select {
+	case wakeWorker <- struct{}{}:
+	default:
+	}
+}
+
+// Stats reports how many workers in this process are busy and idle.
+func Stats() PoolStats {
+	return workers.stats()
+}
+
+func runWorker(owner string) {
+	for {
+		select {
+		case <-workers.quit:
+			return
+		default:
+		}
+		job, err := claimFairly(context.Background(), owner)
+		if err != nil {
+			if !errors.Is(err, mongo.ErrNoDocuments) {
+				log.Printf("[jobs] %s: claim failed: %v", owner, err)
+			}
+			select {
+			case <-workers.quit:
+				return
+			case <-wakeWorker:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		workers.setBusy(1)
+		runLeasedJob(job, owner)
+		workers.setBusy(-1)
+	}
+}
+
+// claimFairly prefers jobs from users who are below their share of running
+// jobs across all processes. Users at the limit are only served when nobody
+// else has work queued, so one account's batch cannot starve the rest while
+// idle workers are still put to use.
+func claimFairly(ctx context.Context, owner string) (*models.Job, error) {
+	busyUsers, err := services.UsersWithLeasedJobs(ctx, perUserLimit)
+	if err != nil {
+		return nil, err
+	}
+	job, err := services.ClaimJob(ctx, owner, leaseDuration, busyUsers)
+	if errors.Is(err, mongo.ErrNoDocuments) && len(busyUsers) > 0 {
+		job, err = services.ClaimJob(ctx, owner, leaseDuration, nil)
+	}
+	return job, err
+}
+
+// runLeasedJob processes a claimed job under its deadline while keeping its
+// lease alive, then acks it. If the process dies before the ack the lease
+// runs out and the job is delivered again. Losing the lease, because the job
+// was cancelled or taken over, stops the scan.
+func runLeasedJob(job *models.Job, owner string) {
+	timeout, key := jobTimeout, job.ReportID
+	if job.Kind == models.JobKindCrawl {
+		timeout, key = crawlTimeout, *job.CrawlID
+	}
+	ctx, cancel := context.WithTimeout(workers.ctx, timeout)
+	defer cancel()
+	inflight.add(key, cancel)
+	defer inflight.remove(key)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := services.ExtendJobLease(context.Background(), job.ID, owner, leaseDuration)
+				if errors.Is(err, services.ErrJobLeaseLost) {
+					log.Printf("[jobs] lost lease on job %s, stopping scan", job.ID.Hex())
+					cancel()
+					return
+				}
+				if err != nil {
+					log.Printf("[jobs] failed to extend lease on job %s: %v", job.ID.Hex(), err)
+				}
+			}
+		}
+	}()
+
+	startedAt := time.Now()
+	var err error
+	switch {
+	case job.Attempts > maxAttempts:
+		// Only reachable when earlier attempts died without reporting back,
+		// e.g. the process crashed mid-scan every time.
+		err = &ScanError{Err: fmt.Errorf("abandoned after %d attempts without a result", job.Attempts-1)}
+	case job.Kind == models.JobKindCrawl:
+		err = processCrawlJob(ctx, job)
+	default:
+		var auth *models.ScanAuth
+		if auth, err = openScanAuth(job); err == nil {
+			err = processAnalyzeJob(ctx, AnalyzeJob{
+				ReportID: job.ReportID,
+				UserID:   job.UserID,
+				URL:      job.URL,
+				HTML:     job.HTML,
+				CrawlID:  job.CrawlID,
+				RescanOf: job.RescanOf,
+				Auth:     auth,
+				Options:  job.Options,
+				Device:   job.Device,
+				Engine:   job.Engine,
+			})
+		}
+	}
+	close(done)
+	interrupted := err != nil && errors.Is(context.Cause(ctx), errWorkerShutdown)
+	switch {
+	case job.Kind == models.JobKindCrawl:
+		settleCrawlJob(job, owner, err, interrupted)
+	case interrupted:
+		checkpointJob(job, owner, startedAt)
+	default:
+		finishJob(job, owner, startedAt, err)
+	}
+}
+
+// checkpointJob hands a job interrupted by shutdown back to the queue.
+func checkpointJob(job *models.Job, owner string, startedAt time.Time) {
+	ctx := context.Background()
+	if err := services.ReleaseJob(ctx, job.ID, owner); err != nil && !errors.Is(err, services.ErrJobLeaseLost) {
+		log.Printf("[jobs] failed to release job %s: %v", job.ID.Hex(), err)
+		return
+	}
+	_ = services.UpdateReportStatus(ctx, job.ReportID, models.ReportStatusQueued)
+	attempt := models.ReportAttempt{
+		Number:     job.Attempts,
+		Worker:     owner,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Outcome:    models.AttemptInterrupted,
+		Error:      "server shut down during the scan",
+		Retryable:  true,
+	}
+	if err := services.AppendReportAttempt(ctx, job.ReportID, attempt); err != nil {
+		log.Printf("[jobs] failed to record attempt for report %s: %v", job.ReportID.Hex(), err)
+	}
+	publishReport(job.ReportID)
+}
+
+// finishJob acks, retries or dead-letters a job depending on how its attempt
+// went, and records the attempt on the report.
+func finishJob(job *models.Job, owner string, startedAt time.Time, err error) {
+	ctx := context.Background()
+	attempt := models.ReportAttempt{
+		Number:     job.Attempts,
+		Worker:     owner,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+	}
+	var ackErr error
+	switch {
+	case err == nil:
+		attempt.Outcome = models.AttemptSucceeded
+		ackErr = services.CompleteJob(ctx, job.ID, owner)
+	case errors.Is(err, errScanCancelled):
+		attempt.Outcome = models.AttemptCancelled
+	default:
+		scanErr := classifyScanError(err)
+		attempt.Error = scanErr.Error()
+		attempt.Retryable = scanErr.Retryable
+		if scanErr.Retryable && job.Attempts < maxAttempts {
+			delay := retryDelay(job.Attempts)
+			attempt.Outcome = models.AttemptRetrying
+			attempt.RetryAt = time.Now().Add(delay)
+			ackErr = services.RetryJob(ctx, job.ID, owner, delay, scanErr.Error())
+			if ackErr == nil {
+				_ = services.UpdateReportStatus(ctx, job.ReportID, models.ReportStatusQueued)
+			}
+			break
+		}
+		attempt.Outcome = models.AttemptFailed
+		reason := scanErr.Error()
+		if scanErr.Retryable {
+			reason = fmt.Sprintf("gave up after %d attempts: %s", job.Attempts, reason)
+		}
+		ackErr = services.DeadLetterJob(ctx, job.ID, owner, reason)
+		if ackErr == nil {
+			_ = services.FailReport(ctx, job.ReportID, reason)
+		}
+	}
+	if ackErr != nil && !errors.Is(ackErr, services.ErrJobLeaseLost) {
+		log.Printf("[jobs] failed to settle job %s: %v", job.ID.Hex(), ackErr)
+	}
+	if err := services.AppendReportAttempt(ctx, job.ReportID, attempt); err != nil {
+		log.Printf("[jobs] failed to record attempt for report %s: %v", job.ReportID.Hex(), err)
+	}
+	publishReport(job.ReportID)
+	if job.CrawlID != nil {
+		if err := services.FinishCrawlIfDone(ctx, *job.CrawlID); err != nil {
+			log.Printf("[jobs] failed to update crawl %s: %v", job.CrawlID.Hex(), err)
+		}
+	}
+}
+
+// retryDelay backs off exponentially from retryBackoff, capped at
+// retryBackoffMax, with up to 20% jitter so retries from one outage spread out.
+func retryDelay(attempt int) time.Duration {
+	delay := retryBackoff << (attempt - 1)
+	if delay <= 0 || delay > retryBackoffMax {
+		delay = retryBackoffMax
+	}
+	return delay + rand.N(delay/5+1)
+}
+
+// inflightJobs tracks the jobs running in this process so they can be
+// cancelled by report id, or crawl id for crawl jobs.
+type inflightJobs struct {
+	mu      sync.Mutex
+	cancels map[primitive.ObjectID]context.CancelFunc
+}
+
+func (f *inflightJobs) add(id primitive.ObjectID, cancel context.CancelFunc) {
+	f.mu.Lock()
+	f.cancels[id] = cancel
+	f.mu.Unlock()
+}
+
+func (f *inflightJobs) remove(id primitive.ObjectID) {
+	f.mu.Lock()
+	delete(f.cancels, id)
+	f.mu.Unlock()
+}
+
+func (f *inflightJobs) cancel(id primitive.ObjectID) {
+	f.mu.Lock()
+	cancel, ok := f.cancels[id]
+	f.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AxeResults mirrors the object returned by axe.run().
+type AxeResults struct {
+	TestEngine      AxeTestEngine      `bson:"testEngine" json:"testEngine"`
+	TestRunner      AxeTestRunner      `bson:"testRunner" json:"testRunner"`
+	TestEnvironment AxeTestEnvironment `bson:"testEnvironment" json:"testEnvironment"`
+	URL             string             `bson:"url" json:"url"`
+	Timestamp       string             `bson:"timestamp" json:"timestamp"`
+	Violations      []AxeRule          `bson:"violations" json:"violations"`
+	Passes          []AxeRule          `bson:"passes" json:"passes"`
+	Incomplete      []AxeRule          `bson:"incomplete" json:"incomplete"`
+	Inapplicable    []AxeRule          `bson:"inapplicable" json:"inapplicable"`
+}
+
+type AxeTestEngine struct {
+	Name    string `bson:"name" json:"name"`
+	Version string `bson:"version" json:"version"`
+}
+
+type AxeTestRunner struct {
+	Name string `bson:"name" json:"name"`
+}
+
+type AxeTestEnvironment struct {
+	UserAgent        string `bson:"userAgent" json:"userAgent"`
+	WindowWidth      int    `bson:"windowWidth" json:"windowWidth"`
+	WindowHeight     int    `bson:"windowHeight" json:"windowHeight"`
+	OrientationAngle int    `bson:"orientationAngle" json:"orientationAngle"`
+	OrientationType  string `bson:"orientationType" json:"orientationType"`
+}
+
+// AxeRule is one rule's outcome, listed under violations, passes, incomplete
+// or inapplicable.
+type AxeRule struct {
+	ID          string    `bson:"id" json:"id"`
+	Impact      string    `bson:"impact" json:"impact"`
+	Tags        []string  `bson:"tags" json:"tags"`
+	Description string    `bson:"description" json:"description"`
+	Help        string    `bson:"help" json:"help"`
+	HelpURL     string    `bson:"helpUrl" json:"helpUrl"`
+	Nodes       []AxeNode `bson:"nodes" json:"nodes"`
+}
+
+// AxeNode is an element a rule was evaluated against.
+type AxeNode struct {
+	HTML           string     `bson:"html" json:"html"`
+	Impact         string     `bson:"impact" json:"impact"`
+	Target         AxeTarget  `bson:"target" json:"target"`
+	Any            []AxeCheck `bson:"any" json:"any"`
+	All            []AxeCheck `bson:"all" json:"all"`
+	None           []AxeCheck `bson:"none" json:"none"`
+	FailureSummary string     `bson:"failureSummary,omitempty" json:"failureSummary,omitempty"`
+	// Screenshot is the id of a cropped image of the element, highlighted.
+	// Only violations get one, and only for elements in the top frame.
+	Screenshot *primitive.ObjectID `bson:"screenshot,omitempty" json:"screenshot,omitempty"`
+	// Fingerprint identifies the same violation across pages and scans; see
+	// services.ViolationFingerprint.
+	Fingerprint string `bson:"fingerprint,omitempty" json:"fingerprint,omitempty"`
+}
+
+type AxeCheck struct {
+	ID           string           `bson:"id" json:"id"`
+	Impact       string           `bson:"impact" json:"impact"`
+	Message      string           `bson:"message" json:"message"`
+	Data         AxeCheckData     `bson:"data" json:"data"`
+	RelatedNodes []AxeRelatedNode `bson:"relatedNodes" json:"relatedNodes"`
+}
+
+type AxeRelatedNode struct {
+	HTML   string    `bson:"html" json:"html"`
+	Target AxeTarget `bson:"target" json:"target"`
+}
+
+// AxeTarget is the selector path to a node, one entry per frame. axe nests an
+// array inside an entry for elements in shadow DOM; those parts are joined
+// with " >>> " so every entry stays a single string.
+type AxeTarget []string
+
+func (t *AxeTarget) UnmarshalJSON(data []byte) error {
+	var parts []interface{}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	*t = flattenTarget(parts)
+	return nil
+}
+
+func (t *AxeTarget) UnmarshalBSONValue(typ bsontype.Type, data []byte) error {
+	if typ == bsontype.Null {
+		*t = nil
+		return nil
+	}
+	var parts []interface{}
+	if err := (bson.RawValue{Type: typ, Value: data}).Unmarshal(&parts); err != nil {
+		return err
+	}
+	*t = flattenTarget(parts)
+	return nil
+}
+
+// String joins the per-frame selectors into one readable path.
+func (t AxeTarget) String() string {
+	return strings.Join(t, " ")
+}
+
+func flattenTarget(parts []interface{}) AxeTarget {
+	target := make(AxeTarget, 0, len(parts))
+	for _, part := range parts {
+		switch p := part.(type) {
+		case string:
+			target = append(target, p)
+		case []interface{}:
+			target = append(target, strings.Join(flattenTarget(p), " >>> "))
+		case primitive.A:
+			target = append(target, strings.Join(flattenTarget(p), " >>> "))
+		}
+	}
+	return target
+}
+
+// AxeCheckData is the free-form data a check attaches to its result. Most
+// checks use an object, some a plain string or array, so the value is kept as
+// decoded JSON (maps, slices and scalars) in both JSON and BSON.
+type AxeCheckData struct {
+	Value interface{}
+}
+
+func (d AxeCheckData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Value)
+}
+
+func (d *AxeCheckData) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &d.Value)
+}
+
+func (d AxeCheckData) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if d.Value == nil {
+		return bsontype.Null, nil, nil
+	}
+	return bson.MarshalValue(d.Value)
+}
+
+func (d *AxeCheckData) UnmarshalBSONValue(typ bsontype.Type, data []byte) error {
+	var v interface{}
+	if err := (bson.RawValue{Type: typ, Value: data}).Unmarshal(&v); err != nil {
+		return err
+	}
+	d.Value = plainBSON(v)
+	return nil
+}
+
+// plainBSON turns the driver's ordered documents and arrays into plain maps
+// and slices so they encode as ordinary JSON objects.
+func plainBSON(v interface{}) interface{} {
+	switch val := v.(type) {
+	case primitive.D:
+		m := make(map[string]interface{}, len(val))
+		for _, e := range val {
+			m[e.Key] = plainBSON(e.Value)
+		}
+		return m
+	case primitive.M:
+		m := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			m[k] = plainBSON(e)
+		}
+		return m
+	case primitive.A:
+		out := make([]interface{}, len(val))
+		for i, e := range val {
+			out[i] = plainBSON(e)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Baseline pins one of a user's reports as the accepted state of a URL. Later
+// scans of the URL with the same engine, device and options are compared
+// against it, so only violations that are new since the baseline count as
+// regressions.
+type Baseline struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	UserID primitive.ObjectID `bson:"userId" json:"userId"`
+	URL    string             `bson:"url" json:"url"`
+	// Variant is the pinned report's services.ScanVariant; empty for a
+	// default scan.
+	Variant   string             `bson:"variant" json:"variant"`
+	ReportID  primitive.ObjectID `bson:"reportId" json:"reportId"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// ContrastFix is computed for an element that failed a color contrast rule:
+// the colors axe measured and, per WCAG level, the closest text and
+// background colors that would pass.
+type ContrastFix struct {
+	RuleID     string    `json:"ruleId"`
+	Target     AxeTarget `json:"target"`
+	HTML       string    `json:"html"`
+	Foreground string    `json:"foreground"`
+	Background string    `json:"background"`
+	Ratio      float64   `json:"ratio"`
+	LargeText  bool      `json:"largeText"`
+	// APCA is the APCA lightness contrast (Lc), when asked for.
+	APCA *float64         `json:"apca,omitempty"`
+	AA   ContrastLevelFix `json:"aa"`
+	AAA  ContrastLevelFix `json:"aaa"`
+}
+
+// ContrastLevelFix is the nearest passing text color with the background
+// kept, and the nearest passing background with the text color kept. Either
+// is empty when no color reaches the level or the pair already passes.
+type ContrastLevelFix struct {
+	Required   float64 `json:"required"`
+	Passes     bool    `json:"passes"`
+	Foreground string  `json:"foreground,omitempty"`
+	Background string  `json:"background,omitempty"`
+}
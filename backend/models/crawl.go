@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CrawlStatus string
+
+const (
+	CrawlStatusQueued   CrawlStatus = "queued"
+	CrawlStatusCrawling CrawlStatus = "crawling"
+	CrawlStatusScanning CrawlStatus = "scanning"
+	CrawlStatusComplete CrawlStatus = "complete"
+	CrawlStatusFailed   CrawlStatus = "failed"
+)
+
+type CrawlSource string
+
+const (
+	// CrawlSourceLinks follows links from StartURL. Crawls stored before
+	// sources existed have no source and are link crawls.
+	CrawlSourceLinks CrawlSource = "links"
+	// CrawlSourceSitemap reads the pages from the sitemap at StartURL.
+	CrawlSourceSitemap CrawlSource = "sitemap"
+	// CrawlSourceList scans an uploaded list of URLs.
+	CrawlSourceList CrawlSource = "list"
+)
+
+// Crawl is a multi-page scan. Discovery, through links or a sitemap, runs as a
+// crawl job that creates one child Report, pointing back through CrawlID, per
+// page it finds; URL lists are queued directly. The crawl is complete once
+// every child report has finished.
+type Crawl struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"_id"`
+	UserID     primitive.ObjectID  `bson:"userId" json:"userId"`
+	Source     CrawlSource         `bson:"source,omitempty" json:"source,omitempty"`
+	ScheduleID *primitive.ObjectID `bson:"scheduleId,omitempty" json:"scheduleId,omitempty"`
+	StartURL   string              `bson:"startUrl,omitempty" json:"startUrl,omitempty"`
+	Domain     string              `bson:"domain" json:"domain"`
+	MaxPages   int                 `bson:"maxPages" json:"maxPages"`
+	MaxDepth   int                 `bson:"maxDepth" json:"maxDepth"`
+	Include    []string            `bson:"include,omitempty" json:"include,omitempty"`
+	Exclude    []string            `bson:"exclude,omitempty" json:"exclude,omitempty"`
+	Status     CrawlStatus         `bson:"status" json:"status"`
+	Discovered int                 `bson:"discovered" json:"discovered"`
+	// Skipped counts listed URLs that were invalid, duplicates, filtered out
+	// by the patterns or over MaxPages.
+	Skipped   int           `bson:"skipped,omitempty" json:"skipped,omitempty"`
+	Summary   *CrawlSummary `bson:"summary,omitempty" json:"summary,omitempty"`
+	Error     string        `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt time.Time     `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time     `bson:"updatedAt" json:"updatedAt"`
+}
+
+// CrawlSummary rolls the child reports of a crawl up to the site level.
+type CrawlSummary struct {
+	Pages    int `bson:"pages" json:"pages"`
+	Finished int `bson:"finished" json:"finished"`
+	Failed   int `bson:"failed" json:"failed"`
+	// AverageScore is the mean score of the pages that have one.
+	AverageScore float64      `bson:"averageScore" json:"averageScore"`
+	Violations   int          `bson:"violations" json:"violations"`
+	Impact       ImpactCounts `bson:"impact" json:"impact"`
+	// TopRules are the most widespread violations, by pages affected.
+	TopRules []CrawlRuleSummary `bson:"topRules" json:"topRules"`
+}
+
+type CrawlRuleSummary struct {
+	ID     string `bson:"id" json:"id"`
+	Impact string `bson:"impact" json:"impact"`
+	Help   string `bson:"help" json:"help"`
+	Pages  int    `bson:"pages" json:"pages"`
+	Nodes  int    `bson:"nodes" json:"nodes"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeviceProfile is the viewport and user agent a page is scanned with.
+type DeviceProfile struct {
+	Name              string  `bson:"name" json:"name"`
+	Width             int     `bson:"width" json:"width"`
+	Height            int     `bson:"height" json:"height"`
+	DeviceScaleFactor float64 `bson:"deviceScaleFactor" json:"deviceScaleFactor"`
+	IsMobile          bool    `bson:"isMobile" json:"isMobile"`
+	HasTouch          bool    `bson:"hasTouch" json:"hasTouch"`
+	// UserAgent replaces the browser's own when set.
+	UserAgent string `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+}
+
+// DeviceCustom names a profile whose settings come with the request.
+const DeviceCustom = "custom"
+
+// DeviceProfiles are the named profiles a scan can ask for.
+var DeviceProfiles = map[string]DeviceProfile{
+	"desktop": {
+		Name:              "desktop",
+		Width:             1366,
+		Height:            768,
+		DeviceScaleFactor: 1,
+	},
+	"tablet": {
+		Name:              "tablet",
+		Width:             810,
+		Height:            1080,
+		DeviceScaleFactor: 2,
+		IsMobile:          true,
+		HasTouch:          true,
+		UserAgent:         "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+	},
+	"mobile": {
+		Name:              "mobile",
+		Width:             390,
+		Height:            844,
+		DeviceScaleFactor: 3,
+		IsMobile:          true,
+		HasTouch:          true,
+		UserAgent:         "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+	},
+}
+
+// ResolveDevice returns the profile called name. For DeviceCustom the
+// settings are taken from custom, which is checked for sane values.
+func ResolveDevice(name string, custom *DeviceProfile) (*DeviceProfile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name != DeviceCustom {
+		profile, ok := DeviceProfiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown device %q, expected desktop, tablet, mobile or custom", name)
+		}
+		return &profile, nil
+	}
+	if custom == nil {
+		return nil, fmt.Errorf("a custom device needs a viewport")
+	}
+	profile := *custom
+	profile.Name = DeviceCustom
+	if profile.DeviceScaleFactor == 0 {
+		profile.DeviceScaleFactor = 1
+	}
+	switch {
+	case profile.Width < 200 || profile.Width > 5000:
+		return nil, fmt.Errorf("viewport width must be between 200 and 5000")
+	case profile.Height < 200 || profile.Height > 5000:
+		return nil, fmt.Errorf("viewport height must be between 200 and 5000")
+	case profile.DeviceScaleFactor < 0.5 || profile.DeviceScaleFactor > 4:
+		return nil, fmt.Errorf("viewport deviceScaleFactor must be between 0.5 and 4")
+	}
+	return &profile, nil
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveDevice(t *testing.T) {
+	tests := []struct {
+		name      string
+		device    string
+		custom    *DeviceProfile
+		wantWidth int
+		wantErr   string
+	}{
+		{name: "desktop", device: "desktop", wantWidth: 1366},
+		{name: "case and spaces", device: " Mobile ", wantWidth: 390},
+		{name: "named ignores viewport", device: "tablet", custom: &DeviceProfile{Width: 100}, wantWidth: 810},
+		{name: "unknown", device: "watch", wantErr: `unknown device "watch"`},
+		{name: "empty", device: "", wantErr: `unknown device ""`},
+		{name: "custom", device: "custom", custom: &DeviceProfile{Width: 1024, Height: 768}, wantWidth: 1024},
+		{name: "custom without viewport", device: "custom", wantErr: "needs a viewport"},
+		{name: "custom too narrow", device: "custom", custom: &DeviceProfile{Width: 199, Height: 768}, wantErr: "width must be between"},
+		{name: "custom too tall", device: "custom", custom: &DeviceProfile{Width: 800, Height: 5001}, wantErr: "height must be between"},
+		{name: "custom scale", device: "custom", custom: &DeviceProfile{Width: 800, Height: 600, DeviceScaleFactor: 8}, wantErr: "deviceScaleFactor must be between"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveDevice(tt.device, tt.custom)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolveDevice(%q) error = %v, want %q", tt.device, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveDevice(%q): %v", tt.device, err)
+			}
+			if got.Width != tt.wantWidth {
+				t.Errorf("ResolveDevice(%q).Width = %d, want %d", tt.device, got.Width, tt.wantWidth)
+			}
+		})
+	}
+}
+
+func TestResolveDeviceCustom(t *testing.T) {
+	custom := &DeviceProfile{Name: "phone", Width: 400, Height: 800, IsMobile: true}
+	got, err := ResolveDevice(DeviceCustom, custom)
+	if err != nil {
+		t.Fatalf("ResolveDevice: %v", err)
+	}
+	if got.Name != DeviceCustom || got.DeviceScaleFactor != 1 || !got.IsMobile {
+		t.Errorf("ResolveDevice = %+v, want a mobile custom profile at scale 1", got)
+	}
+	if custom.Name != "phone" || custom.DeviceScaleFactor != 0 {
+		t.Errorf("ResolveDevice changed the request's viewport: %+v", custom)
+	}
+	// Named profiles are copies, so a scan cannot change the shared table.
+	desktop, _ := ResolveDevice("desktop", nil)
+	desktop.Width = 1
+	if DeviceProfiles["desktop"].Width != 1366 {
+		t.Errorf("changing a resolved profile changed DeviceProfiles")
+	}
+}
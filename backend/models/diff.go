@@ -0,0 +1,26 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// ViolationInstance is one violating node of one rule.
+type ViolationInstance struct {
+	RuleID  string    `bson:"ruleId" json:"ruleId"`
+	Impact  string    `bson:"impact" json:"impact"`
+	Help    string    `bson:"help" json:"help"`
+	HelpURL string    `bson:"helpUrl" json:"helpUrl"`
+	Target  AxeTarget `bson:"target" json:"target"`
+	HTML    string    `bson:"html" json:"html"`
+}
+
+// ReportDiff compares the violations of two scans, from BaseReportID to
+// ReportID.
+type ReportDiff struct {
+	BaseReportID primitive.ObjectID  `json:"baseReportId"`
+	ReportID     primitive.ObjectID  `json:"reportId"`
+	BaseScore    float64             `json:"baseScore"`
+	Score        float64             `json:"score"`
+	ScoreDelta   float64             `json:"scoreDelta"`
+	Introduced   []ViolationInstance `json:"introduced"`
+	Resolved     []ViolationInstance `json:"resolved"`
+	Unchanged    []ViolationInstance `json:"unchanged"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// ScanEngine names what checks a page.
+type ScanEngine string
+
+const (
+	// ScanEngineAxe loads the page in a browser and runs axe-core. Reports and
+	// jobs without an engine used it.
+	ScanEngineAxe ScanEngine = "axe"
+	// ScanEngineStatic checks submitted HTML in Go without a browser. It is
+	// quick but cannot see styles, scripts or layout.
+	ScanEngineStatic ScanEngine = "static"
+)
+
+// IsValid reports whether e is empty or a known engine.
+func (e ScanEngine) IsValid() bool {
+	return e == "" || e == ScanEngineAxe || e == ScanEngineStatic
+}
@@ -0,0 +1,18 @@
+package models
+
+// DomainIssue is one violation, identified by its fingerprint, and the pages
+// of a domain it appears on. Fixing its source once resolves it on all of them.
+type DomainIssue struct {
+	Fingerprint string    `bson:"_id" json:"fingerprint"`
+	RuleID      string    `bson:"ruleId" json:"ruleId"`
+	Impact      string    `bson:"impact" json:"impact"`
+	Help        string    `bson:"help" json:"help"`
+	HelpURL     string    `bson:"helpUrl" json:"helpUrl"`
+	HTML        string    `bson:"html" json:"html"`
+	Target      AxeTarget `bson:"target" json:"target"`
+	Pages       int       `bson:"pages" json:"pages"`
+	Nodes       int       `bson:"nodes" json:"nodes"`
+	// URLs lists up to the first 20 pages with the issue.
+	URLs    []string `bson:"urls" json:"urls"`
+	Summary string   `bson:"-" json:"summary"`
+}
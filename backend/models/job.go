@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusLeased    JobStatus = "leased"
+	JobStatusDone      JobStatus = "done"
+	JobStatusDead      JobStatus = "dead"
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+type JobKind string
+
+const (
+	// JobKindAnalyze scans one report. Jobs stored before kinds existed have
+	// no kind and are analyze jobs.
+	JobKindAnalyze JobKind = "analyze"
+	// JobKindCrawl discovers the pages of a crawl and queues an analyze job
+	// for each.
+	JobKindCrawl JobKind = "crawl"
+)
+
+// Job is a durable unit of work stored in the jobs collection. A worker claims
+// a job by taking a lease on it; if the lease expires before the job is acked
+// it becomes claimable again, so every job is delivered at least once. Jobs
+// that fail for good end up dead-lettered with their last error.
+type Job struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"_id"`
+	Kind           JobKind             `bson:"kind,omitempty" json:"kind,omitempty"`
+	ReportID       primitive.ObjectID  `bson:"reportId" json:"reportId"`
+	CrawlID        *primitive.ObjectID `bson:"crawlId,omitempty" json:"crawlId,omitempty"`
+	RescanOf       *primitive.ObjectID `bson:"rescanOf,omitempty" json:"rescanOf,omitempty"`
+	UserID         primitive.ObjectID  `bson:"userId" json:"userId"`
+	URL            string              `bson:"url" json:"url"`
+	HTML           string              `bson:"html" json:"-"`
+	Options        *ScanOptions        `bson:"options,omitempty" json:"options,omitempty"`
+	Device         *DeviceProfile      `bson:"device,omitempty" json:"device,omitempty"`
+	Engine         ScanEngine          `bson:"engine,omitempty" json:"engine,omitempty"`
+	Status         JobStatus           `bson:"status" json:"status"`
+	Attempts       int                 `bson:"attempts" json:"attempts"`
+	LeaseOwner     string              `bson:"leaseOwner,omitempty" json:"leaseOwner,omitempty"`
+	LeaseExpiresAt time.Time           `bson:"leaseExpiresAt,omitempty" json:"leaseExpiresAt,omitempty"`
+	AvailableAt    time.Time           `bson:"availableAt" json:"availableAt"`
+	LastError      string              `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	DeadAt         time.Time           `bson:"deadAt,omitempty" json:"deadAt,omitempty"`
+	CreatedAt      time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt      time.Time           `bson:"updatedAt" json:"updatedAt"`
+
+	// Auth is the scan's ScanAuth, encrypted. It is removed once the job is
+	// settled; HasAuth remembers that there was one.
+	Auth    string `bson:"auth,omitempty" json:"-"`
+	HasAuth bool   `bson:"hasAuth,omitempty" json:"hasAuth,omitempty"`
+}
@@ -9,19 +9,127 @@ import (
 type ReportStatus string
 
 const (
-	ReportStatusPending  ReportStatus = "pending"
-	ReportStatusComplete ReportStatus = "complete"
-	ReportStatusFailed   ReportStatus = "failed"
+	ReportStatusQueued            ReportStatus = "queued"
+	ReportStatusFetching          ReportStatus = "fetching"
+	ReportStatusScanning          ReportStatus = "scanning"
+	ReportStatusSuggesting        ReportStatus = "suggesting"
+	ReportStatusComplete          ReportStatus = "complete"
+	ReportStatusPartiallyComplete ReportStatus = "partially_complete"
+	ReportStatusFailed            ReportStatus = "failed"
+	ReportStatusCancelled         ReportStatus = "cancelled"
+
+	// Written by earlier versions before scans reported their phase; treated
+	// like queued and scanning respectively.
+	ReportStatusPending ReportStatus = "pending"
+	ReportStatusRunning ReportStatus = "running"
+)
+
+// ActiveReportStatuses are the states of a report whose scan has not finished.
+var ActiveReportStatuses = []ReportStatus{
+	ReportStatusQueued,
+	ReportStatusFetching,
+	ReportStatusScanning,
+	ReportStatusSuggesting,
+	ReportStatusPending,
+	ReportStatusRunning,
+}
+
+// IsFinal reports whether no further transitions will happen.
+func (s ReportStatus) IsFinal() bool {
+	switch s {
+	case ReportStatusComplete, ReportStatusPartiallyComplete, ReportStatusFailed, ReportStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// Progress is a rough percentage of the pipeline done once s is reached.
+func (s ReportStatus) Progress() int {
+	switch s {
+	case ReportStatusFetching:
+		return 20
+	case ReportStatusScanning, ReportStatusRunning:
+		return 50
+	case ReportStatusSuggesting:
+		return 80
+	}
+	if s.IsFinal() {
+		return 100
+	}
+	return 0
+}
+
+type StatusTransition struct {
+	Status ReportStatus `bson:"status" json:"status"`
+	At     time.Time    `bson:"at" json:"at"`
+}
+
+type AttemptOutcome string
+
+const (
+	AttemptSucceeded AttemptOutcome = "succeeded"
+	AttemptRetrying  AttemptOutcome = "retrying"
+	AttemptFailed    AttemptOutcome = "failed"
+	AttemptCancelled AttemptOutcome = "cancelled"
+	// AttemptInterrupted means the server shut down mid-scan and the job was
+	// handed back to the queue without counting against its attempts.
+	AttemptInterrupted AttemptOutcome = "interrupted"
 )
 
+// ReportAttempt records one run of the scan behind a report.
+type ReportAttempt struct {
+	Number     int            `bson:"number" json:"number"`
+	Worker     string         `bson:"worker" json:"worker"`
+	StartedAt  time.Time      `bson:"startedAt" json:"startedAt"`
+	FinishedAt time.Time      `bson:"finishedAt" json:"finishedAt"`
+	Outcome    AttemptOutcome `bson:"outcome" json:"outcome"`
+	Error      string         `bson:"error,omitempty" json:"error,omitempty"`
+	Retryable  bool           `bson:"retryable" json:"retryable"`
+	RetryAt    time.Time      `bson:"retryAt,omitempty" json:"retryAt,omitempty"`
+}
+
 type Report struct {
-	ID              primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
-	UserID          primitive.ObjectID `bson:"userId" json:"userId"`
-	URL             string             `bson:"url" json:"url"`
-	Domain          string             `bson:"domain" json:"domain"` // new field for root domain
-	HTMLSnapshot    string             `bson:"htmlSnapshot" json:"htmlSnapshot"`
-	AnalysisResults interface{}        `bson:"analysisResults" json:"analysisResults"`
-	CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt       time.Time          `bson:"updatedAt" json:"updatedAt"`
-	Status          ReportStatus       `bson:"status" json:"status"`
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"_id"`
+	UserID          primitive.ObjectID  `bson:"userId" json:"userId"`
+	URL             string              `bson:"url" json:"url"`
+	Domain          string              `bson:"domain" json:"domain"` // new field for root domain
+	HTMLSnapshot    string              `bson:"htmlSnapshot" json:"htmlSnapshot"`
+	AnalysisResults *AxeResults         `bson:"analysisResults" json:"analysisResults"`
+	Score           *ReportScore        `bson:"score,omitempty" json:"score,omitempty"`
+	CreatedAt       time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt       time.Time           `bson:"updatedAt" json:"updatedAt"`
+	Status          ReportStatus        `bson:"status" json:"status"`
+	StatusHistory   []StatusTransition  `bson:"statusHistory,omitempty" json:"statusHistory,omitempty"`
+	Progress        int                 `bson:"progress" json:"progress"`
+	Error           string              `bson:"error,omitempty" json:"error,omitempty"`
+	Attempts        []ReportAttempt     `bson:"attempts,omitempty" json:"attempts,omitempty"`
+	CrawlID         *primitive.ObjectID `bson:"crawlId,omitempty" json:"crawlId,omitempty"`
+	ScheduleID      *primitive.ObjectID `bson:"scheduleId,omitempty" json:"scheduleId,omitempty"`
+	// Authenticated is set when the scan ran with credentials; the
+	// credentials themselves are never stored on the report.
+	Authenticated bool `bson:"authenticated,omitempty" json:"authenticated,omitempty"`
+	// ScanOptions are the axe settings the scan ran with, if any.
+	ScanOptions *ScanOptions `bson:"scanOptions,omitempty" json:"scanOptions,omitempty"`
+	// Engine is what checked the page; empty means axe.
+	Engine ScanEngine `bson:"engine,omitempty" json:"engine,omitempty"`
+	// Device is the profile the page was scanned with; nil means the
+	// runner's default viewport.
+	Device *DeviceProfile `bson:"device,omitempty" json:"device,omitempty"`
+	// RenderedDOM is the page as the browser had rendered it when a URL was
+	// scanned, gzip-compressed. GetReportByID leaves it out; use GetReportDOM.
+	RenderedDOM     []byte `bson:"renderedDom,omitempty" json:"-"`
+	RenderedDOMSize int    `bson:"renderedDomSize,omitempty" json:"renderedDomSize,omitempty"`
+	// RescanOf is the report whose stored page this report scanned again. The
+	// page is read from that report when the scan runs rather than copied.
+	RescanOf *primitive.ObjectID `bson:"rescanOf,omitempty" json:"rescanOf,omitempty"`
+	// Screenshot is the id of a full-page image taken when the page was scanned.
+	Screenshot *primitive.ObjectID `bson:"screenshot,omitempty" json:"screenshot,omitempty"`
+	// GroupID ties together the reports of one multi-profile scan.
+	GroupID *primitive.ObjectID `bson:"groupId,omitempty" json:"groupId,omitempty"`
+
+	// Set when the URL had a baseline at the time of the scan. Regressions
+	// lists the violations introduced since that baseline.
+	BaselineID  *primitive.ObjectID `bson:"baselineId,omitempty" json:"baselineId,omitempty"`
+	Regressed   bool                `bson:"regressed" json:"regressed"`
+	Regressions []ViolationInstance `bson:"regressions,omitempty" json:"regressions,omitempty"`
 }
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxLoginSteps bounds a scripted login.
+const maxLoginSteps = 20
+
+// ScanAuth lets a scan reach pages behind a login. It travels to the runner
+// with the scan but is only ever stored encrypted on the job, and never on
+// the report.
+type ScanAuth struct {
+	Cookies    []ScanCookie      `json:"cookies,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	BasicAuth  *BasicAuth        `json:"basicAuth,omitempty"`
+	LoginSteps []LoginStep       `json:"loginSteps,omitempty"`
+}
+
+// ScanCookie is set in the browser before the page loads. Without a Domain it
+// applies to the scanned URL.
+type ScanCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+}
+
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type LoginAction string
+
+const (
+	LoginGoto              LoginAction = "goto"
+	LoginFill              LoginAction = "fill"
+	LoginClick             LoginAction = "click"
+	LoginWaitForSelector   LoginAction = "waitForSelector"
+	LoginWaitForNavigation LoginAction = "waitForNavigation"
+)
+
+// LoginStep is one step of a scripted login, run in order before the page
+// under test is loaded.
+type LoginStep struct {
+	Action   LoginAction `json:"action"`
+	URL      string      `json:"url,omitempty"`
+	Selector string      `json:"selector,omitempty"`
+	Value    string      `json:"value,omitempty"`
+	// TimeoutMs overrides the runner's default wait for this step.
+	TimeoutMs int `json:"timeoutMs,omitempty"`
+}
+
+// IsEmpty reports whether a has nothing to apply.
+func (a *ScanAuth) IsEmpty() bool {
+	return a == nil || (len(a.Cookies) == 0 && len(a.Headers) == 0 && a.BasicAuth == nil && len(a.LoginSteps) == 0)
+}
+
+// Validate checks a for mistakes a runner would only find mid-scan.
+func (a *ScanAuth) Validate() error {
+	for i, c := range a.Cookies {
+		if c.Name == "" {
+			return fmt.Errorf("cookie %d has no name", i+1)
+		}
+	}
+	for name := range a.Headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	if a.BasicAuth != nil && a.BasicAuth.Username == "" {
+		return errors.New("basicAuth needs a username")
+	}
+	if len(a.LoginSteps) > maxLoginSteps {
+		return fmt.Errorf("a login may have at most %d steps", maxLoginSteps)
+	}
+	for i, step := range a.LoginSteps {
+		switch step.Action {
+		case LoginGoto:
+			if !strings.HasPrefix(step.URL, "http://") && !strings.HasPrefix(step.URL, "https://") {
+				return fmt.Errorf("login step %d: goto needs an http or https url", i+1)
+			}
+		case LoginFill, LoginClick, LoginWaitForSelector:
+			if step.Selector == "" {
+				return fmt.Errorf("login step %d: %s needs a selector", i+1, step.Action)
+			}
+		case LoginWaitForNavigation:
+		default:
+			return fmt.Errorf("login step %d: unknown action %q", i+1, step.Action)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScanAuthValidate(t *testing.T) {
+	tooMany := make([]LoginStep, maxLoginSteps+1)
+	for i := range tooMany {
+		tooMany[i] = LoginStep{Action: LoginWaitForNavigation}
+	}
+	tests := []struct {
+		name    string
+		auth    ScanAuth
+		wantErr string
+	}{
+		{"empty", ScanAuth{}, ""},
+		{"cookie", ScanAuth{Cookies: []ScanCookie{{Name: "session", Value: "abc"}}}, ""},
+		{"cookie without name", ScanAuth{Cookies: []ScanCookie{{Name: "a"}, {Value: "abc"}}}, "cookie 2 has no name"},
+		{"header", ScanAuth{Headers: map[string]string{"Authorization": "Bearer x"}}, ""},
+		{"empty header name", ScanAuth{Headers: map[string]string{"": "x"}}, "invalid header name"},
+		{"header name with colon", ScanAuth{Headers: map[string]string{"X-A: b": "x"}}, "invalid header name"},
+		{"header name with newline", ScanAuth{Headers: map[string]string{"X-A\r\nX-B": "x"}}, "invalid header name"},
+		{"basic auth", ScanAuth{BasicAuth: &BasicAuth{Username: "u", Password: "p"}}, ""},
+		{"basic auth without username", ScanAuth{BasicAuth: &BasicAuth{Password: "p"}}, "basicAuth needs a username"},
+		{"login", ScanAuth{LoginSteps: []LoginStep{
+			{Action: LoginGoto, URL: "https://example.com/login"},
+			{Action: LoginFill, Selector: "#user", Value: "me"},
+			{Action: LoginClick, Selector: "button[type=submit]"},
+			{Action: LoginWaitForNavigation},
+			{Action: LoginWaitForSelector, Selector: "#account"},
+		}}, ""},
+		{"goto without scheme", ScanAuth{LoginSteps: []LoginStep{{Action: LoginGoto, URL: "example.com"}}}, "login step 1: goto needs an http or https url"},
+		{"goto javascript", ScanAuth{LoginSteps: []LoginStep{{Action: LoginGoto, URL: "javascript:alert(1)"}}}, "goto needs an http or https url"},
+		{"fill without selector", ScanAuth{LoginSteps: []LoginStep{{Action: LoginWaitForNavigation}, {Action: LoginFill, Value: "x"}}}, "login step 2: fill needs a selector"},
+		{"unknown action", ScanAuth{LoginSteps: []LoginStep{{Action: "type"}}}, `unknown action "type"`},
+		{"too many steps", ScanAuth{LoginSteps: tooMany}, "at most 20 steps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.auth.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScanAuthIsEmpty(t *testing.T) {
+	var nilAuth *ScanAuth
+	if !nilAuth.IsEmpty() || !(&ScanAuth{}).IsEmpty() {
+		t.Errorf("nil or zero ScanAuth is not empty")
+	}
+	if (&ScanAuth{Headers: map[string]string{"X": "y"}}).IsEmpty() {
+		t.Errorf("ScanAuth with a header is empty")
+	}
+}
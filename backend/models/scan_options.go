@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ScanOptions narrows what axe checks on a page. They are saved on the report
+// so the scan can be reproduced.
+type ScanOptions struct {
+	// Standard is the WCAG version and level to test against, e.g. wcag21aa.
+	// Only rules tagged for that level and the levels below it run.
+	Standard string `bson:"standard,omitempty" json:"standard,omitempty"`
+	// BestPractices adds axe's best-practice rules to a Standard.
+	BestPractices bool     `bson:"bestPractices,omitempty" json:"bestPractices,omitempty"`
+	EnableRules   []string `bson:"enableRules,omitempty" json:"enableRules,omitempty"`
+	DisableRules  []string `bson:"disableRules,omitempty" json:"disableRules,omitempty"`
+	// Include and Exclude are CSS selectors limiting which parts of the page
+	// are tested.
+	Include []string `bson:"include,omitempty" json:"include,omitempty"`
+	Exclude []string `bson:"exclude,omitempty" json:"exclude,omitempty"`
+}
+
+// wcagStandard matches standards such as wcag2a, wcag21aa or wcag22aaa.
+var wcagStandard = regexp.MustCompile(`^wcag(2|21|22)(a|aa|aaa)$`)
+
+var ruleID = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// axeWCAGTags are the WCAG tags axe-core uses, lowest version and level first.
+var axeWCAGTags = []string{"wcag2a", "wcag2aa", "wcag2aaa", "wcag21a", "wcag21aa", "wcag22aa"}
+
+// IsEmpty reports whether o leaves axe at its defaults.
+func (o *ScanOptions) IsEmpty() bool {
+	return o == nil || (o.Standard == "" && !o.BestPractices && len(o.EnableRules) == 0 &&
+		len(o.DisableRules) == 0 && len(o.Include) == 0 && len(o.Exclude) == 0)
+}
+
+// Validate checks o and normalises Standard to lower case.
+func (o *ScanOptions) Validate() error {
+	o.Standard = strings.ToLower(strings.TrimSpace(o.Standard))
+	if o.Standard != "" && !wcagStandard.MatchString(o.Standard) {
+		return fmt.Errorf("unknown standard %q, expected e.g. wcag2a, wcag21aa or wcag22aa", o.Standard)
+	}
+	if o.BestPractices && o.Standard == "" {
+		return fmt.Errorf("bestPractices only applies together with a standard")
+	}
+	enabled := map[string]bool{}
+	for _, id := range o.EnableRules {
+		if !ruleID.MatchString(id) {
+			return fmt.Errorf("invalid rule id %q", id)
+		}
+		enabled[id] = true
+	}
+	for _, id := range o.DisableRules {
+		if !ruleID.MatchString(id) {
+			return fmt.Errorf("invalid rule id %q", id)
+		}
+		if enabled[id] {
+			return fmt.Errorf("rule %q is both enabled and disabled", id)
+		}
+	}
+	for _, selectors := range [][]string{o.Include, o.Exclude} {
+		for _, sel := range selectors {
+			if strings.TrimSpace(sel) == "" {
+				return fmt.Errorf("selectors must not be empty")
+			}
+		}
+	}
+	return nil
+}
+
+// StandardTags returns the axe tags a Standard covers: every WCAG tag at or
+// below its version and level, e.g. wcag21aa gives wcag2a, wcag2aa, wcag21a
+// and wcag21aa.
+func (o *ScanOptions) StandardTags() []string {
+	m := wcagStandard.FindStringSubmatch(o.Standard)
+	if m == nil {
+		return nil
+	}
+	version, level := m[1], len(m[2])
+	var tags []string
+	for _, tag := range axeWCAGTags {
+		t := wcagStandard.FindStringSubmatch(tag)
+		if versionRank(t[1]) <= versionRank(version) && len(t[2]) <= level {
+			tags = append(tags, tag)
+		}
+	}
+	if o.BestPractices {
+		tags = append(tags, "best-practice")
+	}
+	return tags
+}
+
+func versionRank(v string) int {
+	switch v {
+	case "21":
+		return 1
+	case "22":
+		return 2
+	}
+	return 0
+}
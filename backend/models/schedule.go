@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MissedRunPolicy string
+
+const (
+	// MissedRunOnce runs a schedule once to catch up when its run was missed,
+	// however many runs were missed.
+	MissedRunOnce MissedRunPolicy = "run_once"
+	// MissedRunSkip drops missed runs and waits for the next one.
+	MissedRunSkip MissedRunPolicy = "skip"
+)
+
+// CrawlConfig is what a schedule needs to start a crawl.
+type CrawlConfig struct {
+	Source   CrawlSource `bson:"source" json:"source"`
+	StartURL string      `bson:"startUrl" json:"startUrl"`
+	MaxPages int         `bson:"maxPages" json:"maxPages"`
+	MaxDepth int         `bson:"maxDepth" json:"maxDepth"`
+	Include  []string    `bson:"include,omitempty" json:"include,omitempty"`
+	Exclude  []string    `bson:"exclude,omitempty" json:"exclude,omitempty"`
+}
+
+// Schedule scans a URL, or runs a crawl, whenever its cron expression fires.
+// Exactly one of URL and Crawl is set.
+type Schedule struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	UserID   primitive.ObjectID `bson:"userId" json:"userId"`
+	Name     string             `bson:"name" json:"name"`
+	Cron     string             `bson:"cron" json:"cron"`
+	Timezone string             `bson:"timezone" json:"timezone"`
+	URL      string             `bson:"url,omitempty" json:"url,omitempty"`
+	Crawl    *CrawlConfig       `bson:"crawl,omitempty" json:"crawl,omitempty"`
+	Enabled  bool               `bson:"enabled" json:"enabled"`
+	// MissedRunPolicy decides what happens to a run that was due while the
+	// server was down.
+	MissedRunPolicy MissedRunPolicy `bson:"missedRunPolicy" json:"missedRunPolicy"`
+	NextRunAt       time.Time       `bson:"nextRunAt" json:"nextRunAt"`
+
+	LastRunAt    time.Time           `bson:"lastRunAt,omitempty" json:"lastRunAt,omitempty"`
+	LastReportID *primitive.ObjectID `bson:"lastReportId,omitempty" json:"lastReportId,omitempty"`
+	LastCrawlID  *primitive.ObjectID `bson:"lastCrawlId,omitempty" json:"lastCrawlId,omitempty"`
+	LastError    string              `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	SkippedRuns  int                 `bson:"skippedRuns" json:"skippedRuns"`
+
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// ReportScore condenses a report's results into one comparable number plus
+// the violation counts behind it. Counts are of violating nodes.
+type ReportScore struct {
+	Score      float64      `bson:"score" json:"score"`
+	Violations int          `bson:"violations" json:"violations"`
+	Passes     int          `bson:"passes" json:"passes"`
+	Impact     ImpactCounts `bson:"impact" json:"impact"`
+	WCAG       WCAGCounts   `bson:"wcag" json:"wcag"`
+}
+
+type ImpactCounts struct {
+	Critical int `bson:"critical" json:"critical"`
+	Serious  int `bson:"serious" json:"serious"`
+	Moderate int `bson:"moderate" json:"moderate"`
+	Minor    int `bson:"minor" json:"minor"`
+}
+
+type WCAGCounts struct {
+	A            int `bson:"a" json:"a"`
+	AA           int `bson:"aa" json:"aa"`
+	AAA          int `bson:"aaa" json:"aaa"`
+	BestPractice int `bson:"bestPractice" json:"bestPractice"`
+	Other        int `bson:"other" json:"other"`
+}
@@ -7,8 +7,13 @@ import (
 	"backend/services"
 	"backend/utils"
 	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -33,6 +38,11 @@ func main() {
 	services.InitUserService(db)
 	services.InitReportService(db)
 	services.InitSuggestionService(db)
+	services.InitJobService(db)
+	services.InitBaselineService(db)
+	services.InitScreenshotService(db)
+	services.InitCrawlService(db)
+	services.InitScheduleService(db)
 
 	r := gin.Default()
 
@@ -43,16 +53,67 @@ func main() {
 	api.RegisterAuthRoutes(r)
 	api.RegisterAnalyzeRoutes(r)
 	api.RegisterReportRoutes(r)
+	api.RegisterJobRoutes(r)
+	api.RegisterBaselineRoutes(r)
+	api.RegisterCrawlRoutes(r)
+	api.RegisterScheduleRoutes(r)
+	api.RegisterDomainRoutes(r)
 
 	// TODO: Register other API routes here
 
-	// Start background worker for analysis jobs
-	jobs.StartAnalyzeWorker()
+	// Start background workers for analysis jobs
+	runner, err := jobs.NewRunnerFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure axe runner: %v", err)
+	}
+	jobs.SetRunner(runner)
+	if err := utils.SecretsKeyError(); errors.Is(err, utils.ErrSecretsKeyTooShort) {
+		log.Printf("Authenticated scanning disabled: %v", err)
+	}
+	if _, _, err := jobs.RecoverOrphanedReports(context.Background()); err != nil {
+		log.Printf("Failed to recover orphaned reports: %v", err)
+	}
+	if err := jobs.RecoverStalledCrawls(context.Background()); err != nil {
+		log.Printf("Failed to recover stalled crawls: %v", err)
+	}
+	jobs.StartAnalyzeWorkers()
+	jobs.StartScheduler()
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
-	log.Printf("Server running on port %s", port)
-	r.Run(":" + port)
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	srv.RegisterOnShutdown(jobs.CloseEventStreams)
+	go func() {
+		log.Printf("Server running on port %s", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	// Wait for SIGINT/SIGTERM, then stop taking requests, let running scans
+	// finish within the grace period and close the Mongo connection.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+
+	grace := utils.EnvDuration("SHUTDOWN_GRACE_PERIOD", 30*time.Second)
+	log.Printf("Shutting down, waiting up to %s for requests and scans", grace)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	jobs.StopScheduler()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown: %v", err)
+	}
+	if err := jobs.StopAnalyzeWorkers(shutdownCtx); err != nil {
+		log.Printf("Analysis workers shutdown: %v", err)
+	}
+	disconnectCtx, cancelDisconnect := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelDisconnect()
+	if err := client.Disconnect(disconnectCtx); err != nil {
+		log.Printf("MongoDB disconnect: %v", err)
+	}
+	log.Println("Server stopped")
 }
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"backend/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var baselineCollection *mongo.Collection
+
+func InitBaselineService(db *mongo.Database) {
+	baselineCollection = db.Collection("baselines")
+	ctx := context.Background()
+	// Baselines pinned before variants existed were keyed by URL alone.
+	_, _ = baselineCollection.UpdateMany(ctx, bson.M{"variant": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"variant": ""}})
+	_, _ = baselineCollection.Indexes().DropOne(ctx, "userId_1_url_1")
+	_, _ = baselineCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "url", Value: 1}, {Key: "variant", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+}
+
+// ScanVariant names the settings that change what a scan of a URL can find:
+// its engine, device and axe options. Reports are only compared with a
+// baseline of the same variant. A default axe scan is the empty variant.
+func ScanVariant(report *models.Report) string {
+	var parts []string
+	if report.Engine != "" && report.Engine != models.ScanEngineAxe {
+		parts = append(parts, "engine="+string(report.Engine))
+	}
+	if d := report.Device; d != nil {
+		if d.Name == models.DeviceCustom {
+			parts = append(parts, "device=custom:"+shortHash(d))
+		} else {
+			parts = append(parts, "device="+d.Name)
+		}
+	}
+	if o := report.ScanOptions; !o.IsEmpty() {
+		// Rule and selector lists are sets; their order does not matter.
+		canonical := *o
+		for _, list := range []*[]string{&canonical.EnableRules, &canonical.DisableRules, &canonical.Include, &canonical.Exclude} {
+			*list = append([]string(nil), *list...)
+			sort.Strings(*list)
+		}
+		parts = append(parts, "options="+shortHash(canonical))
+	}
+	return strings.Join(parts, " ")
+}
+
+func shortHash(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
+
+// baselineURL spells url the way baselines are keyed, so reports created
+// before URLs were normalised still find theirs.
+func baselineURL(url string) string {
+	if normalized, _, err := NormalizeURL(url); err == nil {
+		return normalized
+	}
+	return url
+}
+
+// PinBaseline makes report the baseline for its URL and variant, replacing
+// any earlier baseline the user had pinned for them.
+func PinBaseline(ctx context.Context, report *models.Report) (*models.Baseline, error) {
+	now := time.Now()
+	filter := bson.M{"userId": report.UserID, "url": baselineURL(report.URL), "variant": ScanVariant(report)}
+	update := bson.M{
+		"$set":         bson.M{"reportId": report.ID, "updatedAt": now},
+		"$setOnInsert": bson.M{"createdAt": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var baseline models.Baseline
+	if err := baselineCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&baseline); err != nil {
+		return nil, err
+	}
+	return &baseline, nil
+}
+
+// GetBaseline returns the user's baseline for report's URL and variant, or
+// mongo.ErrNoDocuments.
+func GetBaseline(ctx context.Context, report *models.Report) (*models.Baseline, error) {
+	var baseline models.Baseline
+	filter := bson.M{"userId": report.UserID, "url": baselineURL(report.URL), "variant": ScanVariant(report)}
+	err := baselineCollection.FindOne(ctx, filter).Decode(&baseline)
+	if err != nil {
+		return nil, err
+	}
+	return &baseline, nil
+}
+
+func ListBaselinesByUser(ctx context.Context, userId primitive.ObjectID) ([]models.Baseline, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "url", Value: 1}, {Key: "variant", Value: 1}})
+	cur, err := baselineCollection.Find(ctx, bson.M{"userId": userId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	baselines := []models.Baseline{}
+	if err := cur.All(ctx, &baselines); err != nil {
+		return nil, err
+	}
+	return baselines, nil
+}
+
+// DeleteBaseline unpins a baseline and reports whether the user had one with
+// that id.
+func DeleteBaseline(ctx context.Context, baselineId, userId primitive.ObjectID) (bool, error) {
+	res, err := baselineCollection.DeleteOne(ctx, bson.M{"_id": baselineId, "userId": userId})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+// DeleteBaselinesForReport unpins report wherever it is the baseline.
+func DeleteBaselinesForReport(ctx context.Context, reportId primitive.ObjectID) error {
+	_, err := baselineCollection.DeleteMany(ctx, bson.M{"reportId": reportId})
+	return err
+}
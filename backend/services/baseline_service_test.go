@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+
+	"backend/models"
+)
+
+func TestScanVariant(t *testing.T) {
+	mobile := models.DeviceProfiles["mobile"]
+	custom := models.DeviceProfile{Name: models.DeviceCustom, Width: 1024, Height: 768, DeviceScaleFactor: 1}
+	wider := custom
+	wider.Width = 1280
+	tests := []struct {
+		name   string
+		report models.Report
+		want   string
+	}{
+		{"default", models.Report{}, ""},
+		{"axe named explicitly", models.Report{Engine: models.ScanEngineAxe}, ""},
+		{"empty options", models.Report{ScanOptions: &models.ScanOptions{}}, ""},
+		{"static", models.Report{Engine: models.ScanEngineStatic}, "engine=static"},
+		{"named device", models.Report{Device: &mobile}, "device=mobile"},
+	}
+	for _, tt := range tests {
+		if got := ScanVariant(&tt.report); got != tt.want {
+			t.Errorf("%s: ScanVariant = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	distinct := map[string]models.Report{
+		"default":        {},
+		"static":         {Engine: models.ScanEngineStatic},
+		"mobile":         {Device: &mobile},
+		"custom":         {Device: &custom},
+		"wider custom":   {Device: &wider},
+		"standard":       {ScanOptions: &models.ScanOptions{Standard: "wcag2aa"}},
+		"other standard": {ScanOptions: &models.ScanOptions{Standard: "wcag21aa"}},
+		"excluded":       {ScanOptions: &models.ScanOptions{Standard: "wcag2aa", Exclude: []string{"#ads"}}},
+		"mobile and standard": {
+			Device:      &mobile,
+			ScanOptions: &models.ScanOptions{Standard: "wcag2aa"},
+		},
+	}
+	seen := map[string]string{}
+	for name, report := range distinct {
+		v := ScanVariant(&report)
+		if other, ok := seen[v]; ok {
+			t.Errorf("%s and %s share variant %q", name, other, v)
+		}
+		seen[v] = name
+	}
+}
+
+func TestScanVariantIgnoresListOrder(t *testing.T) {
+	a := &models.ScanOptions{DisableRules: []string{"region", "color-contrast"}, Exclude: []string{"#ads", "footer"}}
+	b := &models.ScanOptions{DisableRules: []string{"color-contrast", "region"}, Exclude: []string{"footer", "#ads"}}
+	if ScanVariant(&models.Report{ScanOptions: a}) != ScanVariant(&models.Report{ScanOptions: b}) {
+		t.Errorf("reordering rules or selectors changed the variant")
+	}
+	if a.DisableRules[0] != "region" || a.Exclude[0] != "#ads" {
+		t.Errorf("ScanVariant reordered the report's options: %+v", a)
+	}
+}
+
+func TestBaselineURL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"HTTPS://Example.com:443/a#top", "https://example.com/a"},
+		{"https://example.com", "https://example.com/"},
+		{"not a url", "not a url"},
+	}
+	for _, tt := range tests {
+		if got := baselineURL(tt.in); got != tt.want {
+			t.Errorf("baselineURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"backend/contrast"
+	"backend/models"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// contrastRules are the axe rules whose checks carry fgColor and bgColor.
+var contrastRules = map[string]bool{"color-contrast": true, "color-contrast-enhanced": true}
+
+// ContrastFixes computes suggested colors for every color contrast failure in
+// results. Nodes whose background axe could not determine, such as text over
+// images, are skipped.
+func ContrastFixes(results *models.AxeResults, withAPCA bool) []models.ContrastFix {
+	fixes := []models.ContrastFix{}
+	if results == nil {
+		return fixes
+	}
+	for _, rule := range results.Violations {
+		if !contrastRules[rule.ID] {
+			continue
+		}
+		for _, node := range rule.Nodes {
+			fix, ok := contrastFix(rule.ID, node, withAPCA)
+			if ok {
+				fixes = append(fixes, fix)
+			}
+		}
+	}
+	return fixes
+}
+
+func contrastFix(ruleID string, node models.AxeNode, withAPCA bool) (models.ContrastFix, bool) {
+	data := contrastData(node)
+	if data == nil {
+		return models.ContrastFix{}, false
+	}
+	fgRaw, _ := data["fgColor"].(string)
+	bgRaw, _ := data["bgColor"].(string)
+	fg, err := contrast.Parse(fgRaw)
+	if err != nil {
+		return models.ContrastFix{}, false
+	}
+	bg, err := contrast.Parse(bgRaw)
+	if err != nil {
+		return models.ContrastFix{}, false
+	}
+	bg = bg.Over(contrast.Color{R: 255, G: 255, B: 255, A: 1})
+	fg = fg.Over(bg)
+	fontSize, _ := data["fontSize"].(string)
+	fontWeight, _ := data["fontWeight"].(string)
+	large := contrast.IsLargeText(fontSizePx(fontSize), isBold(fontWeight))
+	ratio := contrast.Ratio(fg, bg)
+	fix := models.ContrastFix{
+		RuleID:     ruleID,
+		Target:     node.Target,
+		HTML:       node.HTML,
+		Foreground: fg.Hex(),
+		Background: bg.Hex(),
+		Ratio:      math.Round(ratio*100) / 100,
+		LargeText:  large,
+		AA:         levelFix(fg, bg, contrast.Required(contrast.AA, large)),
+		AAA:        levelFix(fg, bg, contrast.Required(contrast.AAA, large)),
+	}
+	if withAPCA {
+		lc := math.Round(contrast.APCA(fg, bg)*10) / 10
+		fix.APCA = &lc
+	}
+	return fix, true
+}
+
+func levelFix(fg, bg contrast.Color, required float64) models.ContrastLevelFix {
+	fix := models.ContrastLevelFix{Required: required, Passes: contrast.Ratio(fg, bg) >= required}
+	if fix.Passes {
+		return fix
+	}
+	if c, ok := contrast.Nearest(fg, bg, required); ok {
+		fix.Foreground = c.Hex()
+	}
+	if c, ok := contrast.Nearest(bg, fg, required); ok {
+		fix.Background = c.Hex()
+	}
+	return fix
+}
+
+// contrastData returns the data of the node's contrast check.
+func contrastData(node models.AxeNode) map[string]interface{} {
+	for _, checks := range [][]models.AxeCheck{node.Any, node.All, node.None} {
+		for _, check := range checks {
+			if data, ok := check.Data.Value.(map[string]interface{}); ok {
+				if _, ok := data["fgColor"]; ok {
+					return data
+				}
+			}
+		}
+	}
+	return nil
+}
+
+var pxSize = regexp.MustCompile(`([\d.]+)px`)
+
+// fontSizePx reads axe's font size, e.g. "12.0pt (16px)", in CSS pixels.
+func fontSizePx(s string) float64 {
+	if m := pxSize.FindStringSubmatch(s); m != nil {
+		v, _ := strconv.ParseFloat(m[1], 64)
+		return v
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+	if pt, err := strconv.ParseFloat(strings.TrimSuffix(fields[0], "pt"), 64); err == nil {
+		return pt * 4 / 3
+	}
+	return 0
+}
+
+func isBold(weight string) bool {
+	if weight == "bold" || weight == "bolder" {
+		return true
+	}
+	n, err := strconv.Atoi(weight)
+	return err == nil && n >= 700
+}
@@ -0,0 +1,41 @@
+package services
+
+import "testing"
+
+func TestFontSizePx(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"12.0pt (16px)", 16},
+		{"18.0pt (24px)", 24},
+		{"10.5pt", 14},
+		{"13.5px", 13.5},
+		{"", 0},
+		{"large", 0},
+	}
+	for _, tt := range tests {
+		if got := fontSizePx(tt.in); got != tt.want {
+			t.Errorf("fontSizePx(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsBold(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"bold", true},
+		{"700", true},
+		{"900", true},
+		{"400", false},
+		{"normal", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isBold(tt.in); got != tt.want {
+			t.Errorf("isBold(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"backend/models"
+	"context"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var crawlCollection *mongo.Collection
+
+// topRuleLimit is how many rules a crawl summary lists.
+const topRuleLimit = 10
+
+func InitCrawlService(db *mongo.Database) {
+	crawlCollection = db.Collection("crawls")
+	_, _ = crawlCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}},
+	})
+}
+
+func CreateCrawl(ctx context.Context, crawl *models.Crawl) error {
+	now := time.Now()
+	crawl.Status = models.CrawlStatusQueued
+	crawl.CreatedAt = now
+	crawl.UpdatedAt = now
+	res, err := crawlCollection.InsertOne(ctx, crawl)
+	if err != nil {
+		return err
+	}
+	crawl.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func GetCrawlByID(ctx context.Context, crawlId primitive.ObjectID) (*models.Crawl, error) {
+	var crawl models.Crawl
+	if err := crawlCollection.FindOne(ctx, bson.M{"_id": crawlId}).Decode(&crawl); err != nil {
+		return nil, err
+	}
+	return &crawl, nil
+}
+
+func ListCrawlsByUser(ctx context.Context, userId primitive.ObjectID) ([]models.Crawl, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cur, err := crawlCollection.Find(ctx, bson.M{"userId": userId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	crawls := []models.Crawl{}
+	if err := cur.All(ctx, &crawls); err != nil {
+		return nil, err
+	}
+	return crawls, nil
+}
+
+// ListCrawlIDsByStatus returns the ids of every crawl in status.
+func ListCrawlIDsByStatus(ctx context.Context, status models.CrawlStatus) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cur, err := crawlCollection.Find(ctx, bson.M{"status": status}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var ids []primitive.ObjectID
+	for cur.Next(ctx) {
+		var row struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cur.Decode(&row); err != nil {
+			continue
+		}
+		ids = append(ids, row.ID)
+	}
+	return ids, cur.Err()
+}
+
+func UpdateCrawlStatus(ctx context.Context, crawlId primitive.ObjectID, status models.CrawlStatus) error {
+	_, err := crawlCollection.UpdateByID(ctx, crawlId, bson.M{"$set": bson.M{"status": status, "updatedAt": time.Now()}})
+	return err
+}
+
+func SetCrawlDiscovered(ctx context.Context, crawlId primitive.ObjectID, discovered int) error {
+	_, err := crawlCollection.UpdateByID(ctx, crawlId, bson.M{"$set": bson.M{"discovered": discovered, "updatedAt": time.Now()}})
+	return err
+}
+
+func SetCrawlSkipped(ctx context.Context, crawlId primitive.ObjectID, skipped int) error {
+	_, err := crawlCollection.UpdateByID(ctx, crawlId, bson.M{"$set": bson.M{"skipped": skipped, "updatedAt": time.Now()}})
+	return err
+}
+
+// FailCrawl marks a crawl whose discovery failed for good. Pages it already
+// queued are still scanned.
+func FailCrawl(ctx context.Context, crawlId primitive.ObjectID, reason string) error {
+	update := bson.M{"$set": bson.M{
+		"status":    models.CrawlStatusFailed,
+		"error":     reason,
+		"updatedAt": time.Now(),
+	}}
+	_, err := crawlCollection.UpdateByID(ctx, crawlId, update)
+	return err
+}
+
+// FinishCrawlIfDone completes a crawl whose discovery is over once none of
+// its reports is still being scanned, and stores its final summary.
+func FinishCrawlIfDone(ctx context.Context, crawlId primitive.ObjectID) error {
+	active, err := reportCollection.CountDocuments(ctx, bson.M{
+		"crawlId": crawlId,
+		"status":  bson.M{"$in": models.ActiveReportStatuses},
+	}, options.Count().SetLimit(1))
+	if err != nil || active > 0 {
+		return err
+	}
+	summary, err := SummarizeCrawl(ctx, crawlId)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"_id": crawlId, "status": models.CrawlStatusScanning}
+	update := bson.M{"$set": bson.M{
+		"status":    models.CrawlStatusComplete,
+		"summary":   summary,
+		"updatedAt": time.Now(),
+	}}
+	_, err = crawlCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// SummarizeCrawl rolls up the current state of a crawl's reports.
+func SummarizeCrawl(ctx context.Context, crawlId primitive.ObjectID) (*models.CrawlSummary, error) {
+	finalStatuses := bson.A{
+		models.ReportStatusComplete,
+		models.ReportStatusPartiallyComplete,
+		models.ReportStatusFailed,
+		models.ReportStatusCancelled,
+	}
+	countIf := func(cond bson.M) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{cond, 1, 0}}}
+	}
+	totals := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"crawlId": crawlId}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          nil,
+			"pages":        bson.M{"$sum": 1},
+			"finished":     countIf(bson.M{"$in": bson.A{"$status", finalStatuses}}),
+			"failed":       countIf(bson.M{"$eq": bson.A{"$status", models.ReportStatusFailed}}),
+			"averageScore": bson.M{"$avg": "$score.score"},
+			"violations":   bson.M{"$sum": "$score.violations"},
+			"critical":     bson.M{"$sum": "$score.impact.critical"},
+			"serious":      bson.M{"$sum": "$score.impact.serious"},
+			"moderate":     bson.M{"$sum": "$score.impact.moderate"},
+			"minor":        bson.M{"$sum": "$score.impact.minor"},
+		}}},
+	}
+	cur, err := reportCollection.Aggregate(ctx, totals)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Pages        int      `bson:"pages"`
+		Finished     int      `bson:"finished"`
+		Failed       int      `bson:"failed"`
+		AverageScore *float64 `bson:"averageScore"`
+		Violations   int      `bson:"violations"`
+		Critical     int      `bson:"critical"`
+		Serious      int      `bson:"serious"`
+		Moderate     int      `bson:"moderate"`
+		Minor        int      `bson:"minor"`
+	}
+	if err := cur.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	summary := &models.CrawlSummary{TopRules: []models.CrawlRuleSummary{}}
+	if len(rows) == 0 {
+		return summary, nil
+	}
+	row := rows[0]
+	summary.Pages = row.Pages
+	summary.Finished = row.Finished
+	summary.Failed = row.Failed
+	if row.AverageScore != nil {
+		summary.AverageScore = math.Round(*row.AverageScore*10) / 10
+	}
+	summary.Violations = row.Violations
+	summary.Impact = models.ImpactCounts{Critical: row.Critical, Serious: row.Serious, Moderate: row.Moderate, Minor: row.Minor}
+
+	rules := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"crawlId": crawlId, "analysisResults": bson.M{"$ne": nil}}}},
+		{{Key: "$unwind", Value: "$analysisResults.violations"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$analysisResults.violations.id",
+			"impact": bson.M{"$first": "$analysisResults.violations.impact"},
+			"help":   bson.M{"$first": "$analysisResults.violations.help"},
+			"pages":  bson.M{"$sum": 1},
+			"nodes":  bson.M{"$sum": bson.M{"$size": bson.M{"$ifNull": bson.A{"$analysisResults.violations.nodes", bson.A{}}}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "pages", Value: -1}, {Key: "nodes", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: topRuleLimit}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "id": "$_id", "impact": 1, "help": 1, "pages": 1, "nodes": 1}}},
+	}
+	cur, err = reportCollection.Aggregate(ctx, rules)
+	if err != nil {
+		return nil, err
+	}
+	if err := cur.All(ctx, &summary.TopRules); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// ListReportsByCrawl lists the pages of a crawl in the order they were found.
+func ListReportsByCrawl(ctx context.Context, crawlId primitive.ObjectID) ([]map[string]interface{}, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"url": 1, "status": 1, "score": 1, "regressed": 1, "error": 1})
+	cur, err := reportCollection.Find(ctx, bson.M{"crawlId": crawlId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	pages := []map[string]interface{}{}
+	for cur.Next(ctx) {
+		var r models.Report
+		if err := cur.Decode(&r); err != nil {
+			continue
+		}
+		pages = append(pages, map[string]interface{}{
+			"_id":       r.ID,
+			"url":       r.URL,
+			"status":    r.Status,
+			"score":     r.Score,
+			"regressed": r.Regressed,
+			"error":     r.Error,
+		})
+	}
+	return pages, nil
+}
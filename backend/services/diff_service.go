@@ -0,0 +1,109 @@
+package services
+
+import (
+	"backend/models"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// DiffReports compares base with report. Violations are paired by rule id
+// and node target first; nodes whose selector changed between scans are then
+// paired by rule id and normalised HTML.
+func DiffReports(base, report *models.Report) models.ReportDiff {
+	diff := models.ReportDiff{
+		BaseReportID: base.ID,
+		ReportID:     report.ID,
+		BaseScore:    reportScore(base).Score,
+		Score:        reportScore(report).Score,
+	}
+	diff.ScoreDelta = math.Round((diff.Score-diff.BaseScore)*10) / 10
+	diff.Introduced, diff.Resolved, diff.Unchanged = DiffViolations(violationInstances(base.AnalysisResults), violationInstances(report.AnalysisResults))
+	return diff
+}
+
+// DiffViolations splits two sets of violation instances into those only in
+// after (introduced), only in before (resolved) and in both (unchanged).
+func DiffViolations(before, after []models.ViolationInstance) (introduced, resolved, unchanged []models.ViolationInstance) {
+	introduced, resolved, unchanged = []models.ViolationInstance{}, []models.ViolationInstance{}, []models.ViolationInstance{}
+	matchedBefore := make([]bool, len(before))
+	matchedAfter := make([]bool, len(after))
+	for _, key := range []func(models.ViolationInstance) string{targetKey, htmlKey} {
+		pending := make(map[string][]int)
+		for i, v := range before {
+			if !matchedBefore[i] {
+				pending[key(v)] = append(pending[key(v)], i)
+			}
+		}
+		for j, v := range after {
+			if matchedAfter[j] {
+				continue
+			}
+			k := key(v)
+			if idx := pending[k]; len(idx) > 0 {
+				matchedBefore[idx[0]] = true
+				matchedAfter[j] = true
+				pending[k] = idx[1:]
+				unchanged = append(unchanged, v)
+			}
+		}
+	}
+	for j, v := range after {
+		if !matchedAfter[j] {
+			introduced = append(introduced, v)
+		}
+	}
+	for i, v := range before {
+		if !matchedBefore[i] {
+			resolved = append(resolved, v)
+		}
+	}
+	return introduced, resolved, unchanged
+}
+
+func violationInstances(results *models.AxeResults) []models.ViolationInstance {
+	if results == nil {
+		return nil
+	}
+	var out []models.ViolationInstance
+	for _, rule := range results.Violations {
+		for _, node := range rule.Nodes {
+			impact := node.Impact
+			if impact == "" {
+				impact = rule.Impact
+			}
+			out = append(out, models.ViolationInstance{
+				RuleID:  rule.ID,
+				Impact:  impact,
+				Help:    rule.Help,
+				HelpURL: rule.HelpURL,
+				Target:  node.Target,
+				HTML:    node.HTML,
+			})
+		}
+	}
+	return out
+}
+
+func reportScore(report *models.Report) models.ReportScore {
+	if report.Score != nil {
+		return *report.Score
+	}
+	return ComputeReportScore(report.AnalysisResults)
+}
+
+func targetKey(v models.ViolationInstance) string {
+	return v.RuleID + "\x00" + v.Target.String()
+}
+
+func htmlKey(v models.ViolationInstance) string {
+	return v.RuleID + "\x00" + normalizeHTML(v.HTML)
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// normalizeHTML collapses whitespace so formatting changes alone do not make
+// a node look new.
+func normalizeHTML(html string) string {
+	return strings.TrimSpace(whitespace.ReplaceAllString(html, " "))
+}
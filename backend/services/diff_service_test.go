@@ -0,0 +1,130 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"backend/models"
+)
+
+func instance(rule, target, markup string) models.ViolationInstance {
+	return models.ViolationInstance{RuleID: rule, Target: models.AxeTarget{target}, HTML: markup}
+}
+
+// targets lists "rule target" for each instance so results compare simply.
+func targets(list []models.ViolationInstance) []string {
+	out := []string{}
+	for _, v := range list {
+		out = append(out, v.RuleID+" "+v.Target.String())
+	}
+	return out
+}
+
+func TestDiffViolations(t *testing.T) {
+	img := instance("image-alt", "#logo", `<img src="logo.png">`)
+	tests := []struct {
+		name       string
+		before     []models.ViolationInstance
+		after      []models.ViolationInstance
+		introduced []string
+		resolved   []string
+		unchanged  []string
+	}{
+		{
+			name: "both nil",
+		},
+		{
+			name:   "both empty",
+			before: []models.ViolationInstance{},
+			after:  []models.ViolationInstance{},
+		},
+		{
+			name:       "nothing before",
+			after:      []models.ViolationInstance{img},
+			introduced: []string{"image-alt #logo"},
+		},
+		{
+			name:     "nothing after",
+			before:   []models.ViolationInstance{img},
+			after:    []models.ViolationInstance{},
+			resolved: []string{"image-alt #logo"},
+		},
+		{
+			name:      "same target, changed markup",
+			before:    []models.ViolationInstance{img},
+			after:     []models.ViolationInstance{instance("image-alt", "#logo", `<img src="logo-2x.png">`)},
+			unchanged: []string{"image-alt #logo"},
+		},
+		{
+			name:      "changed selector, same markup up to whitespace",
+			before:    []models.ViolationInstance{img},
+			after:     []models.ViolationInstance{instance("image-alt", "header > img", "<img\n  src=\"logo.png\">")},
+			unchanged: []string{"image-alt header > img"},
+		},
+		{
+			// Matching by HTML first would pair the new node with #a and
+			// report #b as resolved.
+			name: "target matched before markup",
+			before: []models.ViolationInstance{
+				instance("image-alt", "#a", `<img src="x.png">`),
+				instance("image-alt", "#b", `<img src="y.png">`),
+			},
+			after:     []models.ViolationInstance{instance("image-alt", "#b", `<img src="x.png">`)},
+			resolved:  []string{"image-alt #a"},
+			unchanged: []string{"image-alt #b"},
+		},
+		{
+			name:       "more duplicates after",
+			before:     []models.ViolationInstance{img, img},
+			after:      []models.ViolationInstance{img, img, img},
+			introduced: []string{"image-alt #logo"},
+			unchanged:  []string{"image-alt #logo", "image-alt #logo"},
+		},
+		{
+			name:      "fewer duplicates after",
+			before:    []models.ViolationInstance{img, img},
+			after:     []models.ViolationInstance{img},
+			resolved:  []string{"image-alt #logo"},
+			unchanged: []string{"image-alt #logo"},
+		},
+		{
+			name:       "same node, other rule",
+			before:     []models.ViolationInstance{img},
+			after:      []models.ViolationInstance{instance("role-img-alt", "#logo", `<img src="logo.png">`)},
+			introduced: []string{"role-img-alt #logo"},
+			resolved:   []string{"image-alt #logo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			introduced, resolved, unchanged := DiffViolations(tt.before, tt.after)
+			for _, got := range []struct {
+				label string
+				list  []models.ViolationInstance
+				want  []string
+			}{
+				{"introduced", introduced, tt.introduced},
+				{"resolved", resolved, tt.resolved},
+				{"unchanged", unchanged, tt.unchanged},
+			} {
+				if got.list == nil {
+					t.Errorf("%s is nil, want an empty list", got.label)
+				}
+				want := got.want
+				if want == nil {
+					want = []string{}
+				}
+				if g := targets(got.list); !reflect.DeepEqual(g, want) {
+					t.Errorf("%s = %v, want %v", got.label, g, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffReportsWithoutResults(t *testing.T) {
+	diff := DiffReports(&models.Report{}, &models.Report{})
+	if len(diff.Introduced) != 0 || len(diff.Resolved) != 0 || len(diff.Unchanged) != 0 {
+		t.Errorf("diff of reports without results = %+v, want no violations", diff)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"backend/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	positional = regexp.MustCompile(`:nth-(last-)?(child|of-type)\([^)]*\)`)
+	digits     = regexp.MustCompile(`[0-9]+`)
+)
+
+// shapeAttrs are attributes whose values say what kind of element it is;
+// only the names of other attributes count towards its shape.
+var shapeAttrs = map[string]bool{"class": true, "role": true, "type": true}
+
+// FingerprintResults sets the fingerprint of every violation node.
+func FingerprintResults(results *models.AxeResults) {
+	for i := range results.Violations {
+		rule := &results.Violations[i]
+		for j := range rule.Nodes {
+			rule.Nodes[j].Fingerprint = ViolationFingerprint(rule.ID, rule.Nodes[j])
+		}
+	}
+}
+
+// ViolationFingerprint identifies the same problem on different pages or
+// scans. It hashes the rule with the node's selector and the shape of its
+// start tag, leaving out positions, numbers and text, so a broken template
+// element gets one fingerprint wherever it appears.
+func ViolationFingerprint(ruleID string, node models.AxeNode) string {
+	sum := sha256.Sum256([]byte(ruleID + "\x00" + normalizeSelector(node.Target) + "\x00" + htmlShape(node.HTML)))
+	return hex.EncodeToString(sum[:8])
+}
+
+func normalizeSelector(target models.AxeTarget) string {
+	s := strings.ToLower(target.String())
+	s = positional.ReplaceAllString(s, "")
+	s = digits.ReplaceAllString(s, "0")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// htmlShape describes a node's start tag: its name, the sorted names of its
+// attributes and the values of a few that matter, with numbers blanked.
+func htmlShape(markup string) string {
+	z := html.NewTokenizer(strings.NewReader(markup))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return normalizeHTML(markup)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			attrs := make([]string, 0, len(tok.Attr))
+			for _, a := range tok.Attr {
+				if !shapeAttrs[a.Key] {
+					attrs = append(attrs, a.Key)
+					continue
+				}
+				values := strings.Fields(strings.ToLower(digits.ReplaceAllString(a.Val, "0")))
+				sort.Strings(values)
+				attrs = append(attrs, a.Key+"="+strings.Join(values, " "))
+			}
+			sort.Strings(attrs)
+			return tok.Data + "[" + strings.Join(attrs, "][") + "]"
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+
+	"backend/models"
+)
+
+func node(target, markup string) models.AxeNode {
+	return models.AxeNode{Target: models.AxeTarget{target}, HTML: markup}
+}
+
+func TestViolationFingerprintStable(t *testing.T) {
+	base := node("#main > ul > li:nth-child(2) > a", `<a href="/products/12" class="card-link promo" data-id="12">Shoes</a>`)
+	same := []struct {
+		name string
+		node models.AxeNode
+	}{
+		{"other text", node("#main > ul > li:nth-child(2) > a", `<a href="/products/12" class="card-link promo" data-id="12">Socks on sale</a>`)},
+		{"other attribute values", node("#main > ul > li:nth-child(2) > a", `<a href="/about" class="card-link promo" data-id="abc">Shoes</a>`)},
+		{"other position", node("#main > ul > li:nth-child(7) > a", `<a href="/products/12" class="card-link promo" data-id="12">Shoes</a>`)},
+		{"other numbers", node("#main > ul > li:nth-child(2) > a", `<a href="/products/99" class="card-link promo" data-id="99">Shoes 2</a>`)},
+		{"attribute and class order", node("#main > ul > li:nth-child(2) > a", `<a data-id="12" class="promo card-link" href="/products/12">Shoes</a>`)},
+		{"selector case and spacing", node("#MAIN >  UL > LI > A", `<a href="/products/12" class="card-link promo" data-id="12">Shoes</a>`)},
+	}
+	want := ViolationFingerprint("link-name", base)
+	for _, tt := range same {
+		if got := ViolationFingerprint("link-name", tt.node); got != want {
+			t.Errorf("%s: fingerprint changed from %s to %s", tt.name, want, got)
+		}
+	}
+	numbered := ViolationFingerprint("image-alt", node(".hero-3 img", `<img class="w-100 hero-3" src="/a.png">`))
+	if got := ViolationFingerprint("image-alt", node(".hero-4 img", `<img class="hero-4 w-200" src="/b.png">`)); got != numbered {
+		t.Errorf("numbers in classes changed the fingerprint")
+	}
+}
+
+func TestViolationFingerprintStructural(t *testing.T) {
+	base := node("#main > ul > li > a", `<a href="/products/12" class="card-link">Shoes</a>`)
+	different := []struct {
+		name string
+		rule string
+		node models.AxeNode
+	}{
+		{"other rule", "color-contrast", base},
+		{"other tag", "link-name", node("#main > ul > li > a", `<button class="card-link">Shoes</button>`)},
+		{"added attribute", "link-name", node("#main > ul > li > a", `<a href="/products/12" class="card-link" target="_blank">Shoes</a>`)},
+		{"other class", "link-name", node("#main > ul > li > a", `<a href="/products/12" class="nav-link">Shoes</a>`)},
+		{"other role", "link-name", node("#main > ul > li > a", `<a href="/products/12" class="card-link" role="button">Shoes</a>`)},
+		{"other selector", "link-name", node("#footer > a", `<a href="/products/12" class="card-link">Shoes</a>`)},
+	}
+	want := ViolationFingerprint("link-name", base)
+	for _, tt := range different {
+		if got := ViolationFingerprint(tt.rule, tt.node); got == want {
+			t.Errorf("%s: fingerprint did not change", tt.name)
+		}
+	}
+}
+
+func TestFingerprintResults(t *testing.T) {
+	results := &models.AxeResults{Violations: []models.AxeRule{{
+		ID:    "image-alt",
+		Nodes: []models.AxeNode{node("img", `<img src="a.png">`), node("img", `<img src="b.png">`)},
+	}}}
+	FingerprintResults(results)
+	nodes := results.Violations[0].Nodes
+	if nodes[0].Fingerprint == "" || nodes[0].Fingerprint != nodes[1].Fingerprint {
+		t.Errorf("fingerprints = %q, %q, want the same non-empty value", nodes[0].Fingerprint, nodes[1].Fingerprint)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxIssueURLs caps the example pages listed per issue.
+const maxIssueURLs = 20
+
+// ListDomainIssues groups the violations on a user's pages of one domain by
+// fingerprint, using the latest scan of each page, most widespread first. It
+// also returns how many pages of the domain have been scanned. Nodes scanned
+// before fingerprints existed are left out.
+func ListDomainIssues(ctx context.Context, userId primitive.ObjectID, domain string, minPages, limit int) ([]models.DomainIssue, int, error) {
+	filter := bson.M{"userId": userId, "domain": domain, "analysisResults": bson.M{"$ne": nil}}
+	urls, err := reportCollection.Distinct(ctx, "url", filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        "$url",
+			"violations": bson.M{"$first": "$analysisResults.violations"},
+		}}},
+		{{Key: "$unwind", Value: "$violations"}},
+		{{Key: "$unwind", Value: "$violations.nodes"}},
+		{{Key: "$match", Value: bson.M{"violations.nodes.fingerprint": bson.M{"$nin": bson.A{nil, ""}}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$violations.nodes.fingerprint",
+			"ruleId":  bson.M{"$first": "$violations.id"},
+			"impact":  bson.M{"$first": "$violations.impact"},
+			"help":    bson.M{"$first": "$violations.help"},
+			"helpUrl": bson.M{"$first": "$violations.helpUrl"},
+			"html":    bson.M{"$first": "$violations.nodes.html"},
+			"target":  bson.M{"$first": "$violations.nodes.target"},
+			"urls":    bson.M{"$addToSet": "$_id"},
+			"nodes":   bson.M{"$sum": 1},
+		}}},
+		{{Key: "$addFields", Value: bson.M{"pages": bson.M{"$size": "$urls"}}}},
+		{{Key: "$match", Value: bson.M{"pages": bson.M{"$gte": minPages}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "pages", Value: -1}, {Key: "nodes", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$addFields", Value: bson.M{"urls": bson.M{"$slice": bson.A{"$urls", maxIssueURLs}}}}},
+	}
+	cur, err := reportCollection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, 0, err
+	}
+	issues := []models.DomainIssue{}
+	if err := cur.All(ctx, &issues); err != nil {
+		return nil, 0, err
+	}
+	for i := range issues {
+		issues[i].Summary = fixOnceSummary(issues[i].Pages)
+	}
+	return issues, len(urls), nil
+}
+
+func fixOnceSummary(pages int) string {
+	if pages == 1 {
+		return "Fix once, resolves 1 page"
+	}
+	return fmt.Sprintf("Fix once, resolves %d pages", pages)
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"backend/models"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var jobCollection *mongo.Collection
+
+// ErrJobLeaseLost is returned when a worker touches a job whose lease has
+// expired and been taken over by someone else.
+var ErrJobLeaseLost = errors.New("job lease lost")
+
+// ErrJobActive is returned when queueing a job for a report that already has
+// a queued or leased one.
+var ErrJobActive = errors.New("report already has an active job")
+
+// ErrJobCredentialsDiscarded is returned when requeueing a dead job whose scan
+// credentials were removed when it failed.
+var ErrJobCredentialsDiscarded = errors.New("job credentials were discarded")
+
+func InitJobService(db *mongo.Database) {
+	jobCollection = db.Collection("jobs")
+	_, _ = jobCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "availableAt", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "leaseExpiresAt", Value: 1}}},
+		{Keys: bson.D{{Key: "reportId", Value: 1}}},
+	})
+	// At most one active job per report, so two processes recovering or
+	// requeueing the same report cannot both queue it. Crawl jobs have no
+	// report and are left out by the reportId bound.
+	_, _ = jobCollection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "reportId", Value: 1}},
+		Options: options.Index().
+			SetName("reportId_active_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{
+				"reportId": bson.M{"$gt": primitive.NilObjectID},
+				"status":   bson.M{"$in": bson.A{models.JobStatusQueued, models.JobStatusLeased}},
+			}),
+	})
+}
+
+func CreateJob(ctx context.Context, job *models.Job) error {
+	now := time.Now()
+	job.Status = models.JobStatusQueued
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	if job.AvailableAt.IsZero() {
+		job.AvailableAt = now
+	}
+	res, err := jobCollection.InsertOne(ctx, job)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrJobActive
+	}
+	if err != nil {
+		return err
+	}
+	job.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// ClaimJob leases the oldest available job to owner for the given duration.
+// Jobs whose previous lease has expired are claimable again, and jobs owned by
+// excludeUsers are skipped. It returns mongo.ErrNoDocuments when there is
+// nothing to do.
+func ClaimJob(ctx context.Context, owner string, lease time.Duration, excludeUsers []primitive.ObjectID) (*models.Job, error) {
+	now := time.Now()
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"status": models.JobStatusQueued, "availableAt": bson.M{"$lte": now}},
+			bson.M{"status": models.JobStatusLeased, "leaseExpiresAt": bson.M{"$lte": now}},
+		},
+	}
+	if len(excludeUsers) > 0 {
+		filter["userId"] = bson.M{"$nin": excludeUsers}
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":         models.JobStatusLeased,
+			"leaseOwner":     owner,
+			"leaseExpiresAt": now.Add(lease),
+			"updatedAt":      now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "availableAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+	var job models.Job
+	if err := jobCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// UsersWithLeasedJobs returns the users holding at least limit live leases.
+func UsersWithLeasedJobs(ctx context.Context, limit int) ([]primitive.ObjectID, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": models.JobStatusLeased, "leaseExpiresAt": bson.M{"$gt": time.Now()}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$userId", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gte": limit}}}},
+	}
+	cur, err := jobCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var users []primitive.ObjectID
+	for cur.Next(ctx) {
+		var row struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cur.Decode(&row); err != nil {
+			continue
+		}
+		users = append(users, row.ID)
+	}
+	return users, cur.Err()
+}
+
+func CountJobs(ctx context.Context, filter bson.M) (int64, error) {
+	return jobCollection.CountDocuments(ctx, filter)
+}
+
+// ExtendJobLease pushes the lease deadline forward while owner still holds it.
+func ExtendJobLease(ctx context.Context, jobID primitive.ObjectID, owner string, lease time.Duration) error {
+	now := time.Now()
+	return updateLeasedJob(ctx, jobID, owner, bson.M{
+		"leaseExpiresAt": now.Add(lease),
+		"updatedAt":      now,
+	})
+}
+
+func CompleteJob(ctx context.Context, jobID primitive.ObjectID, owner string) error {
+	return settleLeasedJob(ctx, jobID, owner, bson.M{
+		"status":    models.JobStatusDone,
+		"updatedAt": time.Now(),
+	})
+}
+
+// RetryJob releases the lease and puts the job back in the queue once delay
+// has passed.
+func RetryJob(ctx context.Context, jobID primitive.ObjectID, owner string, delay time.Duration, reason string) error {
+	now := time.Now()
+	return updateLeasedJob(ctx, jobID, owner, bson.M{
+		"status":      models.JobStatusQueued,
+		"availableAt": now.Add(delay),
+		"lastError":   reason,
+		"updatedAt":   now,
+	})
+}
+
+// ReleaseJob gives up a lease early, e.g. on shutdown, and refunds the
+// attempt the claim used.
+func ReleaseJob(ctx context.Context, jobID primitive.ObjectID, owner string) error {
+	filter := bson.M{"_id": jobID, "status": models.JobStatusLeased, "leaseOwner": owner}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.JobStatusQueued,
+			"availableAt": time.Now(),
+			"updatedAt":   time.Now(),
+		},
+		"$inc":   bson.M{"attempts": -1},
+		"$unset": bson.M{"leaseOwner": "", "leaseExpiresAt": ""},
+	}
+	res, err := jobCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+// DeadLetterJob parks a job that failed for good so it can be inspected and
+// requeued by hand. Scan credentials are not kept on dead jobs.
+func DeadLetterJob(ctx context.Context, jobID primitive.ObjectID, owner, reason string) error {
+	now := time.Now()
+	return settleLeasedJob(ctx, jobID, owner, bson.M{
+		"status":    models.JobStatusDead,
+		"lastError": reason,
+		"deadAt":    now,
+		"updatedAt": now,
+	})
+}
+
+func ListJobsByUser(ctx context.Context, userId primitive.ObjectID, status models.JobStatus) ([]models.Job, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "updatedAt", Value: -1}})
+	cur, err := jobCollection.Find(ctx, bson.M{"userId": userId, "status": status}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	jobs := []models.Job{}
+	if err := cur.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// RequeueDeadJob gives a dead-lettered job a fresh set of attempts.
+func RequeueDeadJob(ctx context.Context, jobID, userId primitive.ObjectID) (*models.Job, error) {
+	now := time.Now()
+	// Without its credentials an authenticated scan would only reach the
+	// login page, so those jobs cannot be requeued.
+	filter := bson.M{"_id": jobID, "userId": userId, "status": models.JobStatusDead, "hasAuth": bson.M{"$ne": true}}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.JobStatusQueued,
+			"attempts":    0,
+			"availableAt": now,
+			"updatedAt":   now,
+		},
+		"$unset": bson.M{"deadAt": "", "leaseOwner": "", "leaseExpiresAt": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var job models.Job
+	err := jobCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrJobActive
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		n, countErr := jobCollection.CountDocuments(ctx, bson.M{"_id": jobID, "userId": userId, "status": models.JobStatusDead, "hasAuth": true})
+		if countErr == nil && n > 0 {
+			return nil, ErrJobCredentialsDiscarded
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// HasActiveJob reports whether a report still has a queued or leased job.
+func HasActiveJob(ctx context.Context, reportId primitive.ObjectID) (bool, error) {
+	filter := bson.M{
+		"reportId": reportId,
+		"status":   bson.M{"$in": bson.A{models.JobStatusQueued, models.JobStatusLeased}},
+	}
+	n, err := jobCollection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+// CancelJobsForReport stops queued or leased jobs for a report from being
+// claimed again. Workers holding a lease notice at their next heartbeat.
+func CancelJobsForReport(ctx context.Context, reportId primitive.ObjectID) error {
+	filter := bson.M{
+		"reportId": reportId,
+		"status":   bson.M{"$in": bson.A{models.JobStatusQueued, models.JobStatusLeased}},
+	}
+	_, err := jobCollection.UpdateMany(ctx, filter, bson.M{
+		"$set": bson.M{
+			"status":    models.JobStatusCancelled,
+			"updatedAt": time.Now(),
+		},
+		"$unset": bson.M{"auth": ""},
+	})
+	return err
+}
+
+func updateLeasedJob(ctx context.Context, jobID primitive.ObjectID, owner string, set bson.M) error {
+	return updateLeasedJobWith(ctx, jobID, owner, bson.M{"$set": set})
+}
+
+// settleLeasedJob is updateLeasedJob for a job that will not run again, so
+// its scan credentials are dropped with the same write.
+func settleLeasedJob(ctx context.Context, jobID primitive.ObjectID, owner string, set bson.M) error {
+	return updateLeasedJobWith(ctx, jobID, owner, bson.M{"$set": set, "$unset": bson.M{"auth": ""}})
+}
+
+func updateLeasedJobWith(ctx context.Context, jobID primitive.ObjectID, owner string, update bson.M) error {
+	filter := bson.M{"_id": jobID, "status": models.JobStatusLeased, "leaseOwner": owner}
+	res, err := jobCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
@@ -17,7 +17,7 @@ type LLMResponse struct {
 	Suggestions []string `json:"suggestions"`
 }
 
-func GenerateSuggestionsFromLLM(analysisResults interface{}) ([]models.SuggestionItem, error) {
+func GenerateSuggestionsFromLLM(analysisResults *models.AxeResults) ([]models.SuggestionItem, error) {
 	llmApiUrl := os.Getenv("LLM_API_URL")
 	llmApiKey := os.Getenv("LLM_API_KEY")
 	if llmApiUrl == "" || llmApiKey == "" {
@@ -80,15 +80,9 @@ Violations JSON:
 Write the accessibility analysis according to the AccessibilityAnalysis schema.
 On the response, include only the JSON. No additional text, explanations, or formatting.`
 	// Only send up to 5 violations to the LLM to avoid exceeding request length
-	var violations interface{} = analysisResults
-	if m, ok := analysisResults.(map[string]interface{}); ok {
-		if v, exists := m["violations"]; exists {
-			if arr, ok := v.([]interface{}); ok && len(arr) > 5 {
-				violations = arr[:5]
-			} else {
-				violations = v
-			}
-		}
+	violations := analysisResults.Violations
+	if len(violations) > 5 {
+		violations = violations[:5]
 	}
 
 	// Wrap violations in the expected schema for the LLM prompt
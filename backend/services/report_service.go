@@ -2,13 +2,16 @@ package services
 
 import (
 	"backend/models"
+	"bytes"
+	"compress/gzip"
 	"context"
-	"net/url"
+	"io"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var reportCollection *mongo.Collection
@@ -16,6 +19,13 @@ var suggestionCollection *mongo.Collection
 
 func InitReportService(db *mongo.Database) {
 	reportCollection = db.Collection("reports")
+	_, _ = reportCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "crawlId", Value: 1}, {Key: "url", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "domain", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
 }
 
 func InitSuggestionService(db *mongo.Database) {
@@ -23,20 +33,53 @@ func InitSuggestionService(db *mongo.Database) {
 }
 
 func CreateReport(ctx context.Context, userId primitive.ObjectID, urlStr, html string) (*models.Report, error) {
-	domain := ""
-	if parsed, err := url.Parse(urlStr); err == nil {
-		domain = parsed.Hostname()
+	return InsertReport(ctx, NewReport(userId, urlStr, html))
+}
+
+// CreateCrawlReport adds the report for one page of a crawl. A crawl job that
+// runs again finds the pages it already added, so those are returned instead
+// of being created twice.
+func CreateCrawlReport(ctx context.Context, userId, crawlId primitive.ObjectID, urlStr string) (report *models.Report, created bool, err error) {
+	var existing models.Report
+	err = reportCollection.FindOne(ctx, bson.M{"crawlId": crawlId, "url": urlStr}).Decode(&existing)
+	if err == nil {
+		return &existing, false, nil
 	}
-	report := &models.Report{
+	if err != mongo.ErrNoDocuments {
+		return nil, false, err
+	}
+	report = NewReport(userId, urlStr, "")
+	report.CrawlID = &crawlId
+	report, err = InsertReport(ctx, report)
+	return report, err == nil, err
+}
+
+// CreateScheduledReport adds the report for one run of a schedule.
+func CreateScheduledReport(ctx context.Context, userId, scheduleId primitive.ObjectID, urlStr string) (*models.Report, error) {
+	report := NewReport(userId, urlStr, "")
+	report.ScheduleID = &scheduleId
+	return InsertReport(ctx, report)
+}
+
+// NewReport builds a queued report for a scan of urlStr or html. Callers that
+// need more than CreateReport offers set the extra fields and store it with
+// InsertReport.
+func NewReport(userId primitive.ObjectID, urlStr, html string) *models.Report {
+	now := time.Now()
+	return &models.Report{
 		UserID:          userId,
 		URL:             urlStr,
-		Domain:          domain,
+		Domain:          URLDomain(urlStr),
 		HTMLSnapshot:    html,
 		AnalysisResults: nil,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
-		Status:          models.ReportStatusPending,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Status:          models.ReportStatusQueued,
+		StatusHistory:   []models.StatusTransition{{Status: models.ReportStatusQueued, At: now}},
 	}
+}
+
+func InsertReport(ctx context.Context, report *models.Report) (*models.Report, error) {
 	res, err := reportCollection.InsertOne(ctx, report)
 	if err != nil {
 		return nil, err
@@ -45,29 +88,217 @@ func CreateReport(ctx context.Context, userId primitive.ObjectID, urlStr, html s
 	return report, nil
 }
 
-func UpdateReportResults(ctx context.Context, reportId primitive.ObjectID, results interface{}, status models.ReportStatus) error {
+// UpdateReportResults stores the outcome of a scan. Cancelled reports are left
+// alone so a scan that finishes after its cancellation cannot revive them.
+func UpdateReportResults(ctx context.Context, reportId primitive.ObjectID, results *models.AxeResults, score models.ReportScore, status models.ReportStatus) error {
+	update := statusUpdate(status, bson.M{"analysisResults": results, "score": score})
+	_, err := reportCollection.UpdateOne(ctx, notCancelled(reportId), update)
+	return err
+}
+
+// SetReportScreenshot links the report's full-page image; nil removes it.
+func SetReportScreenshot(ctx context.Context, reportId primitive.ObjectID, imageId *primitive.ObjectID) error {
+	update := bson.M{"$unset": bson.M{"screenshot": ""}}
+	if imageId != nil {
+		update = bson.M{"$set": bson.M{"screenshot": *imageId}}
+	}
+	_, err := reportCollection.UpdateOne(ctx, bson.M{"_id": reportId}, update)
+	return err
+}
+
+// UpdateReportStatus moves a report to status and records the transition.
+// Repeating the current status is a no-op.
+func UpdateReportStatus(ctx context.Context, reportId primitive.ObjectID, status models.ReportStatus) error {
+	filter := bson.M{"_id": reportId, "status": bson.M{"$nin": bson.A{models.ReportStatusCancelled, status}}}
+	_, err := reportCollection.UpdateOne(ctx, filter, statusUpdate(status, nil))
+	return err
+}
+
+// FailReport marks a report failed for good with a reason users can read.
+func FailReport(ctx context.Context, reportId primitive.ObjectID, reason string) error {
+	update := statusUpdate(models.ReportStatusFailed, bson.M{"error": reason})
+	_, err := reportCollection.UpdateOne(ctx, notCancelled(reportId), update)
+	return err
+}
+
+// MarkReportPartial finishes a report whose scan results were saved but whose
+// later steps, such as suggestions, did not complete.
+func MarkReportPartial(ctx context.Context, reportId primitive.ObjectID, reason string) error {
+	update := statusUpdate(models.ReportStatusPartiallyComplete, bson.M{"error": reason})
+	_, err := reportCollection.UpdateOne(ctx, notCancelled(reportId), update)
+	return err
+}
+
+// SetReportRegressions records the comparison of a report with the baseline
+// of its URL.
+func SetReportRegressions(ctx context.Context, reportId, baselineId primitive.ObjectID, regressions []models.ViolationInstance) error {
+	update := bson.M{"$set": bson.M{
+		"baselineId":  baselineId,
+		"regressed":   len(regressions) > 0,
+		"regressions": regressions,
+		"updatedAt":   time.Now(),
+	}}
+	_, err := reportCollection.UpdateOne(ctx, notCancelled(reportId), update)
+	return err
+}
+
+func AppendReportAttempt(ctx context.Context, reportId primitive.ObjectID, attempt models.ReportAttempt) error {
 	update := bson.M{
-		"$set": bson.M{
-			"analysisResults": results,
-			"status":          status,
-			"updatedAt":       time.Now(),
-		},
+		"$push": bson.M{"attempts": attempt},
+		"$set":  bson.M{"updatedAt": time.Now()},
 	}
 	_, err := reportCollection.UpdateByID(ctx, reportId, update)
 	return err
 }
 
+// CancelReport marks a report whose scan has not finished as cancelled and
+// reports whether it did so.
+func CancelReport(ctx context.Context, reportId primitive.ObjectID) (bool, error) {
+	filter := bson.M{
+		"_id":    reportId,
+		"status": bson.M{"$in": models.ActiveReportStatuses},
+	}
+	res, err := reportCollection.UpdateOne(ctx, filter, statusUpdate(models.ReportStatusCancelled, nil))
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// statusUpdate builds an update that sets status, its progress and any extra
+// fields, and appends the transition to the report's history.
+func statusUpdate(status models.ReportStatus, set bson.M) bson.M {
+	now := time.Now()
+	fields := bson.M{
+		"status":    status,
+		"progress":  status.Progress(),
+		"updatedAt": now,
+	}
+	for k, v := range set {
+		fields[k] = v
+	}
+	return bson.M{
+		"$set":  fields,
+		"$push": bson.M{"statusHistory": models.StatusTransition{Status: status, At: now}},
+	}
+}
+
+func notCancelled(reportId primitive.ObjectID) bson.M {
+	return bson.M{"_id": reportId, "status": bson.M{"$ne": models.ReportStatusCancelled}}
+}
+
 func GetReportByID(ctx context.Context, reportId primitive.ObjectID) (*models.Report, error) {
 	var report models.Report
-	err := reportCollection.FindOne(ctx, bson.M{"_id": reportId}).Decode(&report)
+	opts := options.FindOne().SetProjection(bson.M{"renderedDom": 0})
+	err := reportCollection.FindOne(ctx, bson.M{"_id": reportId}, opts).Decode(&report)
 	if err != nil {
 		return nil, err
 	}
 	return &report, nil
 }
 
-func ListReportsByUser(ctx context.Context, userId primitive.ObjectID) ([]map[string]interface{}, error) {
-	cur, err := reportCollection.Find(ctx, bson.M{"userId": userId})
+// SetReportDOM stores the rendered page of a URL scan, gzip-compressed.
+func SetReportDOM(ctx context.Context, reportId primitive.ObjectID, dom string) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(dom)); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	update := bson.M{"$set": bson.M{"renderedDom": buf.Bytes(), "renderedDomSize": len(dom)}}
+	_, err := reportCollection.UpdateOne(ctx, bson.M{"_id": reportId}, update)
+	return err
+}
+
+// GetReportDOM returns the stored rendered page of a report, still
+// gzip-compressed, or nil when there is none.
+func GetReportDOM(ctx context.Context, reportId primitive.ObjectID) ([]byte, error) {
+	var report models.Report
+	opts := options.FindOne().SetProjection(bson.M{"renderedDom": 1})
+	if err := reportCollection.FindOne(ctx, bson.M{"_id": reportId}, opts).Decode(&report); err != nil {
+		return nil, err
+	}
+	return report.RenderedDOM, nil
+}
+
+// ReadReportDOM decompresses a page returned by GetReportDOM.
+func ReadReportDOM(compressed []byte) (string, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	dom, err := io.ReadAll(zr)
+	if err != nil {
+		return "", err
+	}
+	return string(dom), nil
+}
+
+// GetReportPage returns the page a report can be scanned again from: the
+// submitted HTML, or else the rendered page of a URL scan. It is empty when
+// the report kept neither.
+func GetReportPage(ctx context.Context, reportId primitive.ObjectID) (string, error) {
+	var report models.Report
+	opts := options.FindOne().SetProjection(bson.M{"htmlSnapshot": 1, "renderedDom": 1})
+	if err := reportCollection.FindOne(ctx, bson.M{"_id": reportId}, opts).Decode(&report); err != nil {
+		return "", err
+	}
+	if report.HTMLSnapshot != "" || report.RenderedDOM == nil {
+		return report.HTMLSnapshot, nil
+	}
+	return ReadReportDOM(report.RenderedDOM)
+}
+
+// FindStaleReports returns reports in one of statuses that have not been
+// touched since before.
+func FindStaleReports(ctx context.Context, statuses []models.ReportStatus, before time.Time) ([]models.Report, error) {
+	filter := bson.M{
+		"status":    bson.M{"$in": statuses},
+		"updatedAt": bson.M{"$lt": before},
+	}
+	cur, err := reportCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var reports []models.Report
+	if err := cur.All(ctx, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// ReportSortFields maps the sort keys accepted by the report list to the
+// fields they order by.
+var ReportSortFields = map[string]string{
+	"createdAt":  "createdAt",
+	"score":      "score.score",
+	"violations": "score.violations",
+}
+
+// ListReportsByUser lists a user's reports without their results, ordered by
+// sortKey, one of ReportSortFields, newest first by default. A non-nil groupId
+// keeps only the reports of that multi-device scan.
+func ListReportsByUser(ctx context.Context, userId primitive.ObjectID, groupId *primitive.ObjectID, sortKey string, ascending bool) ([]map[string]interface{}, error) {
+	field, ok := ReportSortFields[sortKey]
+	if !ok {
+		field = "createdAt"
+	}
+	order := -1
+	if ascending {
+		order = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: order}, {Key: "_id", Value: order}}).
+		SetProjection(bson.M{"analysisResults": 0, "htmlSnapshot": 0, "renderedDom": 0})
+	filter := bson.M{"userId": userId}
+	if groupId != nil {
+		filter["groupId"] = *groupId
+	}
+	cur, err := reportCollection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -83,11 +314,23 @@ func ListReportsByUser(ctx context.Context, userId primitive.ObjectID) ([]map[st
 			"url":       r.URL,
 			"createdAt": r.CreatedAt,
 			"status":    r.Status,
+			"progress":  r.Progress,
+			"score":     r.Score,
+			"regressed": r.Regressed,
+			"device":    deviceName(r.Device),
+			"groupId":   r.GroupID,
 		})
 	}
 	return reports, nil
 }
 
+func deviceName(device *models.DeviceProfile) string {
+	if device == nil {
+		return ""
+	}
+	return device.Name
+}
+
 func DeleteReportByID(ctx context.Context, reportId primitive.ObjectID) error {
 	_, err := reportCollection.DeleteOne(ctx, bson.M{"_id": reportId})
 	return err
@@ -99,7 +342,15 @@ func CreateSuggestion(ctx context.Context, reportId primitive.ObjectID, suggesti
 		Suggestions: suggestions,
 		CreatedAt:   time.Now(),
 	}
-	_, err := suggestionCollection.InsertOne(ctx, s)
+	// Jobs are delivered at least once, so a re-run replaces earlier suggestions
+	// instead of adding a second document for the same report.
+	opts := options.Replace().SetUpsert(true)
+	_, err := suggestionCollection.ReplaceOne(ctx, bson.M{"reportId": reportId}, s, opts)
+	return err
+}
+
+func DeleteSuggestionsForReport(ctx context.Context, reportId primitive.ObjectID) error {
+	_, err := suggestionCollection.DeleteMany(ctx, bson.M{"reportId": reportId})
 	return err
 }
 
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"backend/models"
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var scheduleCollection *mongo.Collection
+
+func InitScheduleService(db *mongo.Database) {
+	scheduleCollection = db.Collection("schedules")
+	_, _ = scheduleCollection.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "enabled", Value: 1}, {Key: "nextRunAt", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+	})
+}
+
+// ScheduleNext returns when a cron expression fires next after after, in the
+// given IANA timezone (UTC when empty). Standard five-field expressions and
+// descriptors such as @daily are accepted.
+func ScheduleNext(expr, timezone string, after time.Time) (time.Time, error) {
+	sched, loc, err := parseSchedule(expr, timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := sched.Next(after.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never fires", expr)
+	}
+	return next.UTC(), nil
+}
+
+// ScheduleMinGap returns the shortest gap between consecutive firings among
+// the next runs firings after after. Uneven expressions such as "0,1 * * * *"
+// only show their shortest gap part of the time, so a pair of firings is not
+// enough. An expression that fires once returns the largest duration.
+func ScheduleMinGap(expr, timezone string, after time.Time, runs int) (time.Duration, error) {
+	sched, loc, err := parseSchedule(expr, timezone)
+	if err != nil {
+		return 0, err
+	}
+	prev := sched.Next(after.In(loc))
+	if prev.IsZero() {
+		return 0, fmt.Errorf("cron expression %q never fires", expr)
+	}
+	gap := time.Duration(math.MaxInt64)
+	for i := 1; i < runs; i++ {
+		next := sched.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if d := next.Sub(prev); d < gap {
+			gap = d
+		}
+		prev = next
+	}
+	return gap, nil
+}
+
+func parseSchedule(expr, timezone string) (cron.Schedule, *time.Location, error) {
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cron expression: %w", err)
+	}
+	loc := time.UTC
+	if timezone != "" {
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, nil, fmt.Errorf("invalid timezone %q", timezone)
+		}
+	}
+	return sched, loc, nil
+}
+
+func CreateSchedule(ctx context.Context, schedule *models.Schedule) error {
+	now := time.Now()
+	next, err := ScheduleNext(schedule.Cron, schedule.Timezone, now)
+	if err != nil {
+		return err
+	}
+	schedule.NextRunAt = next
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	res, err := scheduleCollection.InsertOne(ctx, schedule)
+	if err != nil {
+		return err
+	}
+	schedule.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func GetScheduleByID(ctx context.Context, scheduleId primitive.ObjectID) (*models.Schedule, error) {
+	var schedule models.Schedule
+	if err := scheduleCollection.FindOne(ctx, bson.M{"_id": scheduleId}).Decode(&schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func ListSchedulesByUser(ctx context.Context, userId primitive.ObjectID) ([]models.Schedule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cur, err := scheduleCollection.Find(ctx, bson.M{"userId": userId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	schedules := []models.Schedule{}
+	if err := cur.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// UpdateSchedule saves the editable fields of a schedule and works out its
+// next run from now, so runs missed while it was disabled are not caught up.
+func UpdateSchedule(ctx context.Context, schedule *models.Schedule) error {
+	now := time.Now()
+	next, err := ScheduleNext(schedule.Cron, schedule.Timezone, now)
+	if err != nil {
+		return err
+	}
+	schedule.NextRunAt = next
+	schedule.UpdatedAt = now
+	set := bson.M{
+		"name":            schedule.Name,
+		"cron":            schedule.Cron,
+		"timezone":        schedule.Timezone,
+		"enabled":         schedule.Enabled,
+		"missedRunPolicy": schedule.MissedRunPolicy,
+		"nextRunAt":       schedule.NextRunAt,
+		"updatedAt":       schedule.UpdatedAt,
+	}
+	unset := bson.M{}
+	if schedule.URL != "" {
+		set["url"] = schedule.URL
+		unset["crawl"] = ""
+	} else {
+		set["crawl"] = schedule.Crawl
+		unset["url"] = ""
+	}
+	filter := bson.M{"_id": schedule.ID, "userId": schedule.UserID}
+	res, err := scheduleCollection.UpdateOne(ctx, filter, bson.M{"$set": set, "$unset": unset})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func DeleteSchedule(ctx context.Context, scheduleId, userId primitive.ObjectID) (bool, error) {
+	res, err := scheduleCollection.DeleteOne(ctx, bson.M{"_id": scheduleId, "userId": userId})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+// DueSchedules returns up to limit enabled schedules whose next run is at or
+// before now, most overdue first.
+func DueSchedules(ctx context.Context, now time.Time, limit int) ([]models.Schedule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "nextRunAt", Value: 1}}).SetLimit(int64(limit))
+	cur, err := scheduleCollection.Find(ctx, bson.M{"enabled": true, "nextRunAt": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var schedules []models.Schedule
+	if err := cur.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// ClaimScheduleRun moves a due schedule on to its next run and reports
+// whether this caller did so. Only one of several schedulers racing for the
+// same run wins, so each run is started once.
+func ClaimScheduleRun(ctx context.Context, schedule *models.Schedule, next time.Time) (bool, error) {
+	filter := bson.M{"_id": schedule.ID, "enabled": true, "nextRunAt": schedule.NextRunAt}
+	update := bson.M{"$set": bson.M{"nextRunAt": next, "updatedAt": time.Now()}}
+	res, err := scheduleCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// RecordScheduleRun stores what a run started, or why it could not start.
+func RecordScheduleRun(ctx context.Context, scheduleId primitive.ObjectID, reportId, crawlId *primitive.ObjectID, runErr error) error {
+	now := time.Now()
+	set := bson.M{"lastRunAt": now, "updatedAt": now}
+	unset := bson.M{}
+	if runErr != nil {
+		set["lastError"] = runErr.Error()
+	} else {
+		unset["lastError"] = ""
+	}
+	if reportId != nil {
+		set["lastReportId"] = reportId
+	}
+	if crawlId != nil {
+		set["lastCrawlId"] = crawlId
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	_, err := scheduleCollection.UpdateByID(ctx, scheduleId, update)
+	return err
+}
+
+// RecordSkippedRun counts a run dropped under MissedRunSkip.
+func RecordSkippedRun(ctx context.Context, scheduleId primitive.ObjectID) error {
+	update := bson.M{"$inc": bson.M{"skippedRuns": 1}, "$set": bson.M{"updatedAt": time.Now()}}
+	_, err := scheduleCollection.UpdateByID(ctx, scheduleId, update)
+	return err
+}
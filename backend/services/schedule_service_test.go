@@ -0,0 +1,85 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	after := time.Date(2026, 3, 7, 10, 30, 0, 0, time.UTC) // a Saturday
+	tests := []struct {
+		expr     string
+		timezone string
+		want     time.Time
+	}{
+		{"*/15 * * * *", "", time.Date(2026, 3, 7, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * 1", "", time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC)},
+		{"@daily", "", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", "", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		// 09:00 in Berlin is 08:00 UTC before the switch to summer time.
+		{"0 9 * * *", "Europe/Berlin", time.Date(2026, 3, 8, 8, 0, 0, 0, time.UTC)},
+		{"0 9 * * *", "America/New_York", time.Date(2026, 3, 7, 14, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ScheduleNext(tt.expr, tt.timezone, after)
+		if err != nil {
+			t.Errorf("ScheduleNext(%q, %q): %v", tt.expr, tt.timezone, err)
+			continue
+		}
+		if !got.Equal(tt.want) || got.Location() != time.UTC {
+			t.Errorf("ScheduleNext(%q, %q) = %v, want %v", tt.expr, tt.timezone, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleNextErrors(t *testing.T) {
+	tests := []struct {
+		expr     string
+		timezone string
+		wantErr  string
+	}{
+		{"", "", "invalid cron expression"},
+		{"* * * *", "", "invalid cron expression"},
+		{"61 * * * *", "", "invalid cron expression"},
+		{"0 0 * * *", "Mars/Olympus", "invalid timezone"},
+		{"0 0 30 2 *", "", "never fires"},
+	}
+	for _, tt := range tests {
+		if _, err := ScheduleNext(tt.expr, tt.timezone, time.Now()); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ScheduleNext(%q, %q) error = %v, want %q", tt.expr, tt.timezone, err, tt.wantErr)
+		}
+		if _, err := ScheduleMinGap(tt.expr, tt.timezone, time.Now(), 10); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ScheduleMinGap(%q, %q) error = %v, want %q", tt.expr, tt.timezone, err, tt.wantErr)
+		}
+	}
+}
+
+func TestScheduleMinGap(t *testing.T) {
+	// Half past the hour: the next two firings of "0,1 * * * *" are an hour
+	// apart, the pair after that a minute.
+	after := time.Date(2026, 3, 7, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Duration
+	}{
+		{"*/5 * * * *", 5 * time.Minute},
+		{"0,1 * * * *", time.Minute},
+		{"0 * * * *", time.Hour},
+		// Hours 0, 7, 14 and 21: the gap over midnight is three hours.
+		{"0 */7 * * *", 3 * time.Hour},
+		{"0 9 * * 1-5", 24 * time.Hour},
+		// The 31st and the 1st are a day apart at the end of some months.
+		{"0 0 1,31 * *", 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := ScheduleMinGap(tt.expr, "", after, 2000)
+		if err != nil {
+			t.Errorf("ScheduleMinGap(%q): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ScheduleMinGap(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
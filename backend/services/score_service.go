@@ -0,0 +1,105 @@
+package services
+
+import (
+	"backend/models"
+	"math"
+	"strings"
+)
+
+// impactWeights is how much one violating node of each impact counts against
+// the score, relative to one passing node.
+var impactWeights = map[string]float64{
+	"critical": 10,
+	"serious":  5,
+	"moderate": 2,
+	"minor":    1,
+}
+
+// ComputeReportScore scores results from 0 to 100 as passing nodes over
+// passing nodes plus impact-weighted violating nodes, so a single critical
+// issue weighs as much as ten minor ones. A page with nothing to check scores
+// 100.
+func ComputeReportScore(results *models.AxeResults) models.ReportScore {
+	var score models.ReportScore
+	if results == nil {
+		score.Score = 100
+		return score
+	}
+	penalty := 0.0
+	for _, rule := range results.Violations {
+		level := wcagLevel(rule.Tags)
+		for _, node := range rule.Nodes {
+			impact := node.Impact
+			if impact == "" {
+				impact = rule.Impact
+			}
+			weight, ok := impactWeights[impact]
+			if !ok {
+				weight = impactWeights["moderate"]
+			}
+			penalty += weight
+			score.Violations++
+			countImpact(&score.Impact, impact)
+			countLevel(&score.WCAG, level)
+		}
+	}
+	for _, rule := range results.Passes {
+		score.Passes += len(rule.Nodes)
+	}
+	total := float64(score.Passes) + penalty
+	if total == 0 {
+		score.Score = 100
+		return score
+	}
+	score.Score = math.Round(1000*float64(score.Passes)/total) / 10
+	return score
+}
+
+func countImpact(counts *models.ImpactCounts, impact string) {
+	switch impact {
+	case "critical":
+		counts.Critical++
+	case "serious":
+		counts.Serious++
+	case "minor":
+		counts.Minor++
+	default:
+		counts.Moderate++
+	}
+}
+
+func countLevel(counts *models.WCAGCounts, level string) {
+	switch level {
+	case "A":
+		counts.A++
+	case "AA":
+		counts.AA++
+	case "AAA":
+		counts.AAA++
+	case "best-practice":
+		counts.BestPractice++
+	default:
+		counts.Other++
+	}
+}
+
+// wcagLevel reads the conformance level from axe tags such as wcag2a,
+// wcag21aa or wcag2aaa. Rules tagged only best-practice report that instead.
+func wcagLevel(tags []string) string {
+	level := ""
+	for _, tag := range tags {
+		if tag == "best-practice" && level == "" {
+			level = "best-practice"
+			continue
+		}
+		if !strings.HasPrefix(tag, "wcag") {
+			continue
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(tag, "wcag"), "0123456789")
+		switch rest {
+		case "a", "aa", "aaa":
+			return strings.ToUpper(rest)
+		}
+	}
+	return level
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"testing"
+
+	"backend/models"
+)
+
+func rule(id, impact string, tags []string, nodes ...models.AxeNode) models.AxeRule {
+	return models.AxeRule{ID: id, Impact: impact, Tags: tags, Nodes: nodes}
+}
+
+func nodes(n int) []models.AxeNode {
+	return make([]models.AxeNode, n)
+}
+
+func TestComputeReportScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		results *models.AxeResults
+		want    models.ReportScore
+	}{
+		{
+			name:    "no results",
+			results: nil,
+			want:    models.ReportScore{Score: 100},
+		},
+		{
+			name:    "nothing checked",
+			results: &models.AxeResults{},
+			want:    models.ReportScore{Score: 100},
+		},
+		{
+			name: "clean",
+			results: &models.AxeResults{
+				Passes: []models.AxeRule{rule("html-has-lang", "", nil, nodes(1)...), rule("image-alt", "", nil, nodes(9)...)},
+			},
+			want: models.ReportScore{Score: 100, Passes: 10},
+		},
+		{
+			name: "one critical",
+			results: &models.AxeResults{
+				Violations: []models.AxeRule{rule("image-alt", "critical", []string{"cat.text-alternatives", "wcag2a", "wcag111"}, models.AxeNode{})},
+				Passes:     []models.AxeRule{rule("html-has-lang", "", nil, nodes(10)...)},
+			},
+			want: models.ReportScore{
+				Score:      50,
+				Violations: 1,
+				Passes:     10,
+				Impact:     models.ImpactCounts{Critical: 1},
+				WCAG:       models.WCAGCounts{A: 1},
+			},
+		},
+		{
+			// serious 5 + minor node override 1 + moderate 2 + unknown impact
+			// counted as moderate 2 = 10 against 20 passes.
+			name: "mixed impact",
+			results: &models.AxeResults{
+				Violations: []models.AxeRule{
+					rule("color-contrast", "serious", []string{"wcag2aa", "wcag143"}, models.AxeNode{}, models.AxeNode{Impact: "minor"}),
+					rule("region", "moderate", []string{"best-practice"}, models.AxeNode{}),
+					rule("color-contrast-enhanced", "", []string{"wcag2aaa", "wcag146"}, models.AxeNode{}),
+				},
+				Passes: []models.AxeRule{rule("document-title", "", nil, nodes(20)...)},
+			},
+			want: models.ReportScore{
+				Score:      66.7,
+				Violations: 4,
+				Passes:     20,
+				Impact:     models.ImpactCounts{Serious: 1, Moderate: 2, Minor: 1},
+				WCAG:       models.WCAGCounts{AA: 2, AAA: 1, BestPractice: 1},
+			},
+		},
+		{
+			name: "only violations",
+			results: &models.AxeResults{
+				Violations: []models.AxeRule{rule("bypass", "serious", []string{"section508"}, models.AxeNode{})},
+			},
+			want: models.ReportScore{
+				Score:      0,
+				Violations: 1,
+				Impact:     models.ImpactCounts{Serious: 1},
+				WCAG:       models.WCAGCounts{Other: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ComputeReportScore(tt.results); got != tt.want {
+				t.Errorf("ComputeReportScore() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWCAGLevel(t *testing.T) {
+	tests := []struct {
+		tags []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"wcag2a", "wcag111"}, "A"},
+		{[]string{"wcag21aa", "wcag1410"}, "AA"},
+		{[]string{"wcag2aaa"}, "AAA"},
+		{[]string{"cat.color", "wcag143", "wcag2aa"}, "AA"},
+		{[]string{"best-practice"}, "best-practice"},
+		{[]string{"best-practice", "wcag2a"}, "A"},
+		{[]string{"section508", "section508.22.a"}, ""},
+		{[]string{"wcag2a-obsolete"}, ""},
+	}
+	for _, tt := range tests {
+		if got := wcagLevel(tt.tags); got != tt.want {
+			t.Errorf("wcagLevel(%v) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var screenshotBucket *gridfs.Bucket
+
+// ErrScreenshotNotFound is returned for an image that does not exist or
+// belongs to another report.
+var ErrScreenshotNotFound = errors.New("screenshot not found")
+
+// InitScreenshotService stores report screenshots in the "screenshots"
+// GridFS bucket.
+func InitScreenshotService(db *mongo.Database) {
+	bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName("screenshots"))
+	if err != nil {
+		panic(err)
+	}
+	screenshotBucket = bucket
+	_, _ = bucket.GetFilesCollection().Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "metadata.reportId", Value: 1}},
+	})
+}
+
+// SaveScreenshot stores one image of a report and returns its id.
+func SaveScreenshot(ctx context.Context, reportId primitive.ObjectID, name, contentType string, data []byte) (primitive.ObjectID, error) {
+	id := primitive.NewObjectID()
+	opts := options.GridFSUpload().SetMetadata(bson.M{"reportId": reportId, "contentType": contentType})
+	stream, err := screenshotBucket.OpenUploadStreamWithID(id, name, opts)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetWriteDeadline(deadline)
+	}
+	if _, err := stream.Write(data); err != nil {
+		_ = stream.Abort()
+		return primitive.NilObjectID, err
+	}
+	if err := stream.Close(); err != nil {
+		return primitive.NilObjectID, err
+	}
+	return id, nil
+}
+
+// OpenScreenshot opens an image of the report for reading and returns its
+// content type. The caller closes the stream.
+func OpenScreenshot(ctx context.Context, reportId, imageId primitive.ObjectID) (*gridfs.DownloadStream, string, error) {
+	var file struct {
+		Metadata struct {
+			ContentType string `bson:"contentType"`
+		} `bson:"metadata"`
+	}
+	filter := bson.M{"_id": imageId, "metadata.reportId": reportId}
+	err := screenshotBucket.GetFilesCollection().FindOne(ctx, filter).Decode(&file)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, "", ErrScreenshotNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	stream, err := screenshotBucket.OpenDownloadStream(imageId)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, "", ErrScreenshotNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetReadDeadline(deadline)
+	}
+	return stream, file.Metadata.ContentType, nil
+}
+
+// DeleteScreenshotsForReport removes every image stored for a report.
+func DeleteScreenshotsForReport(ctx context.Context, reportId primitive.ObjectID) error {
+	cur, err := screenshotBucket.FindContext(ctx, bson.M{"metadata.reportId": reportId})
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var file struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cur.Decode(&file); err != nil {
+			continue
+		}
+		if err := screenshotBucket.DeleteContext(ctx, file.ID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			return err
+		}
+	}
+	return cur.Err()
+}
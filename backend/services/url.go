@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URLDomain is the domain stored on a report for urlStr: its hostname, or ""
+// when it does not parse.
+func URLDomain(urlStr string) string {
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
+
+// NormalizeURL checks that raw is an absolute http(s) URL and spells it one
+// way: lower-case scheme and host, no default port, no fragment and at least
+// "/" as the path. It also returns the URL's domain as URLDomain would.
+func NormalizeURL(raw string) (normalized, domain string, err error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid url %q", raw)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return "", "", fmt.Errorf("invalid url %q: must be an absolute http or https URL", raw)
+	}
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	u.Host = host
+	if strings.Contains(host, ":") {
+		// IPv6 literals keep their brackets.
+		u.Host = "[" + host + "]"
+	}
+	if port != "" {
+		u.Host += ":" + port
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), URLDomain(u.String()), nil
+}
+
+// NormalizeURLs normalises urls and drops duplicates, keeping the first
+// occurrence. Entries that are not http(s) URLs count as skipped.
+func NormalizeURLs(urls []string) (normalized []string, skipped int) {
+	seen := make(map[string]bool, len(urls))
+	for _, raw := range urls {
+		u, _, err := NormalizeURL(raw)
+		if err != nil || seen[u] {
+			skipped++
+			continue
+		}
+		seen[u] = true
+		normalized = append(normalized, u)
+	}
+	return normalized, skipped
+}
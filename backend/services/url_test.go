@@ -0,0 +1,49 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in, want, domain string
+	}{
+		{"https://Example.COM", "https://example.com/", "example.com"},
+		{"  HTTP://example.com:80/a?b=1#frag ", "http://example.com/a?b=1", "example.com"},
+		{"https://example.com:443/", "https://example.com/", "example.com"},
+		{"https://example.com:8443/x/", "https://example.com:8443/x/", "example.com"},
+		{"http://example.com:443/", "http://example.com:443/", "example.com"},
+		{"http://[2001:DB8::1]:80/", "http://[2001:db8::1]/", "2001:db8::1"},
+		{"https://example.com/Path/Case", "https://example.com/Path/Case", "example.com"},
+	}
+	for _, tt := range tests {
+		got, domain, err := NormalizeURL(tt.in)
+		if err != nil {
+			t.Errorf("NormalizeURL(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want || domain != tt.domain {
+			t.Errorf("NormalizeURL(%q) = %q, %q, want %q, %q", tt.in, got, domain, tt.want, tt.domain)
+		}
+	}
+	for _, in := range []string{"", "example.com", "/relative", "ftp://example.com/", "mailto:a@example.com", "https://", "http://%zz/"} {
+		if got, _, err := NormalizeURL(in); err == nil {
+			t.Errorf("NormalizeURL(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestNormalizeURLs(t *testing.T) {
+	got, skipped := NormalizeURLs([]string{
+		"https://example.com",
+		"https://EXAMPLE.com/#top",
+		"not a url",
+		"https://example.com/b",
+		"https://example.com:443/b",
+	})
+	want := []string{"https://example.com/", "https://example.com/b"}
+	if !reflect.DeepEqual(got, want) || skipped != 3 {
+		t.Errorf("NormalizeURLs = %v, %d skipped, want %v, 3 skipped", got, skipped, want)
+	}
+}
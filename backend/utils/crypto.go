@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// minSecretsKeyLen is the shortest SCAN_SECRETS_KEY accepted.
+const minSecretsKeyLen = 16
+
+// ErrSecretsKeyMissing is returned when SCAN_SECRETS_KEY is not configured.
+var ErrSecretsKeyMissing = errors.New("SCAN_SECRETS_KEY environment variable not set")
+
+// ErrSecretsKeyTooShort is returned when SCAN_SECRETS_KEY is too short to be
+// a real secret.
+var ErrSecretsKeyTooShort = fmt.Errorf("SCAN_SECRETS_KEY must be at least %d characters", minSecretsKeyLen)
+
+// SecretsKeyError reports why EncryptSecret cannot be used, or nil if it can.
+func SecretsKeyError() error {
+	secret := os.Getenv("SCAN_SECRETS_KEY")
+	if secret == "" {
+		return ErrSecretsKeyMissing
+	}
+	if len(secret) < minSecretsKeyLen {
+		return ErrSecretsKeyTooShort
+	}
+	return nil
+}
+
+// SecretsConfigured reports whether EncryptSecret can be used.
+func SecretsConfigured() bool {
+	return SecretsKeyError() == nil
+}
+
+// secretsAEAD derives an AES-256-GCM cipher from SCAN_SECRETS_KEY. Any long
+// enough string works as the key; it is hashed to the right length.
+func secretsAEAD() (cipher.AEAD, error) {
+	if err := SecretsKeyError(); err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256([]byte(os.Getenv("SCAN_SECRETS_KEY")))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptSecret seals plaintext with AES-GCM under SCAN_SECRETS_KEY and
+// returns the nonce and ciphertext, base64 encoded.
+func EncryptSecret(plaintext []byte) (string, error) {
+	aead, err := secretsAEAD()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret opens a value produced by EncryptSecret.
+func DecryptSecret(encoded string) ([]byte, error) {
+	aead, err := secretsAEAD()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted secret is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+const testSecretsKey = "0123456789abcdef0123456789abcdef"
+
+func TestEncryptSecretRoundTrip(t *testing.T) {
+	t.Setenv("SCAN_SECRETS_KEY", testSecretsKey)
+	for _, plaintext := range [][]byte{[]byte(`{"headers":{"Authorization":"Bearer x"}}`), {}} {
+		sealed, err := EncryptSecret(plaintext)
+		if err != nil {
+			t.Fatalf("EncryptSecret: %v", err)
+		}
+		if len(plaintext) > 0 && bytes.Contains([]byte(sealed), plaintext) {
+			t.Errorf("sealed value %q contains the plaintext", sealed)
+		}
+		opened, err := DecryptSecret(sealed)
+		if err != nil {
+			t.Fatalf("DecryptSecret: %v", err)
+		}
+		if !bytes.Equal(opened, plaintext) {
+			t.Errorf("DecryptSecret = %q, want %q", opened, plaintext)
+		}
+	}
+	a, _ := EncryptSecret([]byte("same"))
+	b, _ := EncryptSecret([]byte("same"))
+	if a == b {
+		t.Errorf("sealing the same value twice gave the same output; nonces are not random")
+	}
+}
+
+func TestDecryptSecretRejects(t *testing.T) {
+	t.Setenv("SCAN_SECRETS_KEY", testSecretsKey)
+	sealed, err := EncryptSecret([]byte("secret"))
+	if err != nil {
+		t.Fatalf("EncryptSecret: %v", err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(sealed)
+	tampered := append([]byte{}, raw...)
+	tampered[len(tampered)-1] ^= 1
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"not base64", "not base64!"},
+		{"empty", ""},
+		{"shorter than a nonce", base64.StdEncoding.EncodeToString([]byte("short"))},
+		{"tampered", base64.StdEncoding.EncodeToString(tampered)},
+	}
+	for _, tt := range tests {
+		if _, err := DecryptSecret(tt.value); err == nil {
+			t.Errorf("%s: DecryptSecret succeeded, want an error", tt.name)
+		}
+	}
+
+	t.Setenv("SCAN_SECRETS_KEY", "another key of sufficient length")
+	if _, err := DecryptSecret(sealed); err == nil {
+		t.Errorf("DecryptSecret under another key succeeded, want an error")
+	}
+}
+
+func TestSecretsKeyError(t *testing.T) {
+	tests := []struct {
+		key  string
+		want error
+	}{
+		{"", ErrSecretsKeyMissing},
+		{"short", ErrSecretsKeyTooShort},
+		{"fifteen chars!!", ErrSecretsKeyTooShort},
+		{"sixteen chars!!!", nil},
+		{testSecretsKey, nil},
+	}
+	for _, tt := range tests {
+		t.Setenv("SCAN_SECRETS_KEY", tt.key)
+		if err := SecretsKeyError(); !errors.Is(err, tt.want) {
+			t.Errorf("SecretsKeyError() with key %q = %v, want %v", tt.key, err, tt.want)
+		}
+		if got := SecretsConfigured(); got != (tt.want == nil) {
+			t.Errorf("SecretsConfigured() with key %q = %v", tt.key, got)
+		}
+		if tt.want == nil {
+			continue
+		}
+		if _, err := EncryptSecret([]byte("x")); !errors.Is(err, tt.want) {
+			t.Errorf("EncryptSecret with key %q error = %v, want %v", tt.key, err, tt.want)
+		}
+		if _, err := DecryptSecret("AAAA"); !errors.Is(err, tt.want) {
+			t.Errorf("DecryptSecret with key %q error = %v, want %v", tt.key, err, tt.want)
+		}
+	}
+}